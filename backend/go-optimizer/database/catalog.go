@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Names of the documents stored in the optimizer_catalog table.
+const (
+	SelectableCombinationsCatalog = "selectableCombinations"
+	TradeStrategiesCatalog        = "tradeStrategies"
+	TradingSessionsCatalog        = "tradingSessions"
+)
+
+// errCatalogTableMissing is returned when the optimizer_catalog table has not been created yet.
+var errCatalogTableMissing = errors.New("optimizer_catalog table does not exist")
+
+// LoadCatalogs reads the criteria, strategy and session catalogs from the optimizer_catalog
+// table. A missing document is seeded from the given built-in catalog; a document stored
+// by an older seed version is upgraded to it by upgradeCatalogDocument. If the table itself
+// does not exist yet, the built-in catalogs are returned unchanged. The returned changes
+// describe every added entry, the warnings every stored entry that differs from the seed.
+func (db *DB) LoadCatalogs(seedVersion int, defaultCombinations, defaultStrategies, defaultSessions []map[string]interface{}) (combinations, strategies, sessions []map[string]interface{}, changes, warnings []string, err error) {
+	combinations, combinationChanges, combinationWarnings, err := db.loadCatalogDocument(SelectableCombinationsCatalog, defaultCombinations, seedVersion)
+	if errors.Is(err, errCatalogTableMissing) {
+		return defaultCombinations, defaultStrategies, defaultSessions, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	strategies, strategyChanges, strategyWarnings, err := db.loadCatalogDocument(TradeStrategiesCatalog, defaultStrategies, seedVersion)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	sessions, sessionChanges, sessionWarnings, err := db.loadCatalogDocument(TradingSessionsCatalog, defaultSessions, seedVersion)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	changes = append(append(combinationChanges, strategyChanges...), sessionChanges...)
+	warnings = append(append(combinationWarnings, strategyWarnings...), sessionWarnings...)
+	return combinations, strategies, sessions, changes, warnings, nil
+}
+
+// loadCatalogDocument fetches a single catalog document, inserting the seed if it is missing
+// and upgrading it if it was stored by an older seed version.
+func (db *DB) loadCatalogDocument(name string, seed []map[string]interface{}, seedVersion int) ([]map[string]interface{}, []string, []string, error) {
+	var documentJSON []byte
+	var storedVersion int
+	err := db.Pool.QueryRow(context.Background(),
+		"SELECT document, version FROM optimizer_catalog WHERE name=$1", name,
+	).Scan(&documentJSON, &storedVersion)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42P01" { // undefined_table
+		return nil, nil, nil, errCatalogTableMissing
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return seed, nil, nil, db.seedCatalogDocument(name, seed, seedVersion)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not load catalog %q: %w", name, err)
+	}
+
+	var document []map[string]interface{}
+	if err := json.Unmarshal(documentJSON, &document); err != nil {
+		return nil, nil, nil, fmt.Errorf("could not unmarshal catalog %q: %w", name, err)
+	}
+	if storedVersion >= seedVersion {
+		return document, nil, nil, nil
+	}
+
+	document, added, differing, err := upgradeCatalogDocument(document, seed)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not upgrade catalog %q: %w", name, err)
+	}
+	var changes, warnings []string
+	for _, entry := range added {
+		changes = append(changes, fmt.Sprintf("%s: added %q", name, entry))
+	}
+	for _, entry := range differing {
+		warnings = append(warnings, fmt.Sprintf("Catalog %s: %q differs from the built-in entry of seed version %d and was kept; built-in changes to it do not apply.", name, entry, seedVersion))
+	}
+	if err := db.storeCatalogDocument(name, document, seedVersion); err != nil {
+		return nil, nil, nil, err
+	}
+	return document, changes, warnings, nil
+}
+
+// upgradeCatalogDocument brings a stored catalog up to the built-in seed by entry name: a
+// built-in entry missing from the document is added, and every stored entry is kept as it
+// is, since it may have been edited by an analyst. It returns the names of the added
+// entries and of the stored entries that differ from the built-in ones.
+func upgradeCatalogDocument(document, seed []map[string]interface{}) ([]map[string]interface{}, []string, []string, error) {
+	index := make(map[string]int)
+	for i, entry := range document {
+		if name, ok := entry["name"].(string); ok {
+			index[name] = i
+		}
+	}
+
+	var added, differing []string
+	for _, entry := range seed {
+		name, _ := entry["name"].(string)
+		i, stored := index[name]
+		if !stored {
+			document = append(document, entry)
+			added = append(added, name)
+			continue
+		}
+		seedJSON, err := json.Marshal(entry)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		storedJSON, err := json.Marshal(document[i])
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if string(seedJSON) != string(storedJSON) {
+			differing = append(differing, name)
+		}
+	}
+	return document, added, differing, nil
+}
+
+// seedCatalogDocument stores a built-in catalog so it can be edited without a redeploy.
+func (db *DB) seedCatalogDocument(name string, seed []map[string]interface{}, seedVersion int) error {
+	seedJSON, err := json.Marshal(seed)
+	if err != nil {
+		return fmt.Errorf("could not marshal seed for catalog %q: %w", name, err)
+	}
+	_, err = db.Pool.Exec(context.Background(),
+		`INSERT INTO optimizer_catalog (name, document, version) VALUES ($1, $2, $3) ON CONFLICT (name) DO NOTHING`,
+		name, seedJSON, seedVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("could not seed catalog %q: %w", name, err)
+	}
+	return nil
+}
+
+// storeCatalogDocument replaces a stored catalog with its upgraded version.
+func (db *DB) storeCatalogDocument(name string, document []map[string]interface{}, seedVersion int) error {
+	documentJSON, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("could not marshal catalog %q: %w", name, err)
+	}
+	_, err = db.Pool.Exec(context.Background(),
+		`UPDATE optimizer_catalog SET document=$2, version=$3, "updatedAt"=now() WHERE name=$1`,
+		name, documentJSON, seedVersion,
+	)
+	if err != nil {
+		return fmt.Errorf("could not store upgraded catalog %q: %w", name, err)
+	}
+	return nil
+}
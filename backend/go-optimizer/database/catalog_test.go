@@ -0,0 +1,39 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpgradeCatalogDocument(t *testing.T) {
+	seed := []map[string]interface{}{
+		{"name": "A", "value": 1.0},
+		{"name": "B", "value": 2.0},
+		{"name": "C", "value": 3.0},
+	}
+	document := []map[string]interface{}{
+		{"name": "A", "value": 1.0},
+		{"name": "B", "value": 20.0},
+		{"name": "Custom", "value": 5.0},
+	}
+
+	upgraded, added, differing, err := upgradeCatalogDocument(document, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"name": "A", "value": 1.0},
+		{"name": "B", "value": 20.0},
+		{"name": "Custom", "value": 5.0},
+		{"name": "C", "value": 3.0},
+	}
+	if !reflect.DeepEqual(upgraded, want) {
+		t.Errorf("upgraded document = %v, want %v", upgraded, want)
+	}
+	if !reflect.DeepEqual(added, []string{"C"}) {
+		t.Errorf("added = %v, want [C]", added)
+	}
+	if !reflect.DeepEqual(differing, []string{"B"}) {
+		t.Errorf("differing = %v, want [B]", differing)
+	}
+}
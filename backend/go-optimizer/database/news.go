@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-optimizer/optimizer"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+// FetchNewsEvents reads the economic calendar from the news_event table. A missing
+// table yields no events.
+func (db *DB) FetchNewsEvents() ([]optimizer.NewsEvent, error) {
+	rows, err := db.Pool.Query(context.Background(),
+		"SELECT timestamp, currency, impact FROM news_event ORDER BY timestamp",
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42P01" { // undefined_table
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying news events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []optimizer.NewsEvent
+	for rows.Next() {
+		var event optimizer.NewsEvent
+		var timestamp time.Time
+		if err := rows.Scan(&timestamp, &event.Currency, &event.Impact); err != nil {
+			return nil, fmt.Errorf("error scanning news event: %w", err)
+		}
+		event.Time = timestamp.UTC()
+		event.Currency = strings.ToUpper(event.Currency)
+		event.Impact = strings.ToLower(event.Impact)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"go-optimizer/optimizer" // Assuming module name is go-optimizer
+	"strings"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -39,51 +40,95 @@ func (db *DB) FetchConfiguration(configID int) (optimizer.Configuration, error)
 	return config, nil
 }
 
-// FetchAllTrades retrieves all trades for a given timeframe from the database.
-func (db *DB) FetchAllTrades(instrument string, timeframe string) ([]optimizer.Trade, error) {
-	query := `
-	SELECT 
-		"Time", "Setup", "Direction", "Entered", "Canceled_After_Candles", "Breakout_Candle_Count", "Candle_Size",
-		"Breakout_Distance", "Entry_Distance", "Entry_Candle_Has_Wick", "Closed_In_LTA", "TP_1RR_PW_WIN",
-		"TP_1RR_STR_WIN", "TP_1RR_PW_PIPS", "TP_1RR_STR_PIPS", "SL_PW_PIPS", "SL_STR_PIPS",
-		"LTA_Range_Breakout", "Nearest_Range_Breakout", "Static_Range_Breakout", "Current_Range_Breakout",
-		"TP_SR_LTA_SL_PW_WIN", "TP_SR_LTA_PIPS", "TP_SR_LTA_SL_STR_WIN", "TP_SR_NEAREST_SL_PW_WIN",
-		"TP_SR_NEAREST_SL_STR_WIN", "TP_SR_NEAREST_PIPS", "TP_SR_STATIC_SL_PW_WIN",
-		"TP_SR_STATIC_SL_STR_WIN", "TP_SR_STATIC_PIPS", "TP_SR_CURRENT_PW_WIN",
-		"TP_SR_CURRENT_STR_WIN", "TP_SR_CURRENT_PIPS", "M10_Candle", "M15_Candle", "M30_Candle",
-		"H1_Candle", "H4_Candle", "D1_Candle", "M10_Candle_Open", "M15_Candle_Open", "M30_Candle_Open",
-		"H1_Candle_Open", "H4_Candle_Open", "D1_Candle_Open", "S2_Previous_Support_Distance", "S2_Previous_Resistance_Distance", "S3_Reversal_Candle_Size"
+// tradeColumn is a column of the trade table as reported by information_schema.
+type tradeColumn struct {
+	Name     string
+	DataType string
+}
+
+// fetchTradeTableColumns introspects the columns of the trade table in table order.
+func (db *DB) fetchTradeTableColumns() ([]tradeColumn, error) {
+	rows, err := db.Pool.Query(context.Background(),
+		"SELECT column_name, data_type FROM information_schema.columns WHERE table_name = 'trade' ORDER BY ordinal_position",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying trade table columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []tradeColumn
+	for rows.Next() {
+		var col tradeColumn
+		if err := rows.Scan(&col.Name, &col.DataType); err != nil {
+			return nil, fmt.Errorf("error scanning column definition: %w", err)
+		}
+		columns = append(columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during column iteration: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("trade table has no columns or does not exist")
+	}
+	return columns, nil
+}
+
+// columnSelect maps a Postgres data type to the column kind it is loaded as and the
+// select expression that produces a matching Go value.
+func columnSelect(col tradeColumn) (optimizer.ColumnKind, string) {
+	quoted := fmt.Sprintf("%q", col.Name)
+	switch col.DataType {
+	case "boolean":
+		return optimizer.BoolColumn, quoted
+	case "smallint", "integer", "bigint":
+		return optimizer.IntColumn, quoted
+	case "real", "double precision", "numeric":
+		return optimizer.FloatColumn, quoted + "::double precision"
+	default:
+		// Text columns and anything else (dates, timestamps, json) are loaded as strings.
+		return optimizer.StringColumn, quoted + "::text"
+	}
+}
+
+// FetchAllTrades retrieves all trades for a given timeframe from the database. Every column
+// of the trade table is loaded, so a column added to the Node entity is available to
+// criteria and strategies without changes on the Go side.
+func (db *DB) FetchAllTrades(instrument string, timeframe string) (*optimizer.TradeTable, error) {
+	columns, err := db.fetchTradeTableColumns()
+	if err != nil {
+		return nil, err
+	}
+
+	table := optimizer.NewTradeTable()
+	var selects []string
+	for _, col := range columns {
+		kind, expr := columnSelect(col)
+		table.AddColumn(col.Name, kind)
+		selects = append(selects, expr)
+	}
+
+	query := fmt.Sprintf(`
+	SELECT %s
 	FROM trade 
-	WHERE timeframe=$1 AND instrument=$2`
+	WHERE timeframe=$1 AND instrument=$2
+	ORDER BY id`, strings.Join(selects, ", "))
 	rows, err := db.Pool.Query(context.Background(), query, timeframe, instrument)
 	if err != nil {
 		return nil, fmt.Errorf("error querying trades: %w", err)
 	}
 	defer rows.Close()
 
-	var trades []optimizer.Trade
 	for rows.Next() {
-		var t optimizer.Trade
-		// The Scan must match the query order exactly.
-		err := rows.Scan(
-			&t.Time, &t.Setup, &t.Direction, &t.Entered, &t.Canceled_After_Candles, &t.Breakout_Candle_Count, &t.Candle_Size,
-			&t.Breakout_Distance, &t.Entry_Distance, &t.Entry_Candle_Has_Wick, &t.Closed_In_LTA, &t.TP_1RR_PW_WIN,
-			&t.TP_1RR_STR_WIN, &t.TP_1RR_PW_PIPS, &t.TP_1RR_STR_PIPS, &t.SL_PW_PIPS, &t.SL_STR_PIPS,
-			&t.LTA_Range_Breakout, &t.Nearest_Range_Breakout, &t.Static_Range_Breakout, &t.Current_Range_Breakout,
-			&t.TP_SR_LTA_SL_PW_WIN, &t.TP_SR_LTA_PIPS, &t.TP_SR_LTA_SL_STR_WIN, &t.TP_SR_NEAREST_SL_PW_WIN,
-			&t.TP_SR_NEAREST_SL_STR_WIN, &t.TP_SR_NEAREST_PIPS, &t.TP_SR_STATIC_SL_PW_WIN,
-			&t.TP_SR_STATIC_SL_STR_WIN, &t.TP_SR_STATIC_PIPS, &t.TP_SR_CURRENT_PW_WIN,
-			&t.TP_SR_CURRENT_STR_WIN, &t.TP_SR_CURRENT_PIPS, &t.M10_Candle, &t.M15_Candle, &t.M30_Candle,
-			&t.H1_Candle, &t.H4_Candle, &t.D1_Candle, &t.M10_Candle_Open, &t.M15_Candle_Open, &t.M30_Candle_Open,
-			&t.H1_Candle_Open, &t.H4_Candle_Open, &t.D1_Candle_Open, &t.S2_Previous_Support_Distance, &t.S2_Previous_Resistance_Distance, &t.S3_Reversal_Candle_Size,
-		)
+		values, err := rows.Values()
 		if err != nil {
 			return nil, fmt.Errorf("error scanning trade row: %w", err)
 		}
-		trades = append(trades, t)
+		if err := table.AppendRow(values); err != nil {
+			return nil, fmt.Errorf("error storing trade row: %w", err)
+		}
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error during rows iteration: %w", err)
 	}
-	return trades, nil
+	return table, nil
 }
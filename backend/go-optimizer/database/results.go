@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// InstrumentColumnLength is the length of the optimization_result instrument column, which
+// holds the comma-separated instruments of a run (OptimizationResult.ts).
+const InstrumentColumnLength = 100
+
+// InsertOptimizationResult stores a finished run in the optimization_result table in the
+// same shape the Node orchestrator stores the printed output: the results array and the
+// rest of the output as summary, and the trade details written to a side file otherwise. A
+// nil summary or trade details are stored as NULL. It returns the new row id.
+func (db *DB) InsertOptimizationResult(instrument string, configID int, results, summary, tradeDetails []byte, startedAt time.Time) (int, error) {
+	ctx := context.Background()
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not begin result transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var summaryValue, tradeDetailsValue interface{}
+	if summary != nil {
+		summaryValue = string(summary)
+	}
+	if tradeDetails != nil {
+		tradeDetailsValue = string(tradeDetails)
+	}
+	var id int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO optimization_result (instrument, "configurationId", results, summary, "tradeDetails", "startedAt")
+		VALUES ($1, $2, $3::jsonb, $4::jsonb, $5::jsonb, $6)
+		RETURNING id`,
+		instrument, configID, string(results), summaryValue, tradeDetailsValue, startedAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("could not insert optimization result: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("could not commit optimization result: %w", err)
+	}
+	return id, nil
+}
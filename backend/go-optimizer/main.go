@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"go-optimizer/database"
@@ -8,14 +10,20 @@ import (
 	"go-optimizer/reporting"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var debugLog = log.New(os.Stderr, "[Go-Optimizer-Debug] ", log.Ltime)
 
+// version identifies the build in the run summary, set with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	startTime := time.Now()
 	debugLog.Printf("--- GO OPTIMIZER ENGINE STARTED at %s ----", startTime.Format(time.RFC3339))
@@ -25,7 +33,7 @@ func main() {
 	}()
 
 	// --- 1. System and Environment Setup ---
-	instrument, configID, jobID, numWorkers := parseArgsAndSetup()
+	instruments, configID, jobID, numWorkers := parseArgsAndSetup()
 	dbURL, redisURL := getEnvVars()
 
 	// --- 2. Initial Data Loading ---
@@ -35,32 +43,135 @@ func main() {
 	}
 	defer db.Pool.Close()
 
+	combinationsCatalog, strategiesCatalog, sessionsCatalog, catalogChanges, catalogWarnings, err := db.LoadCatalogs(optimizer.CatalogSeedVersion, optimizer.SelectableCombinations, optimizer.TradeStrategies, optimizer.TradingSessions)
+	if err != nil {
+		debugLog.Fatalf("Failed to load catalogs: %v", err)
+	}
+	for _, change := range catalogChanges {
+		debugLog.Printf("Catalog upgraded to seed version %d: %s", optimizer.CatalogSeedVersion, change)
+	}
+	if err := optimizer.SetCatalogs(combinationsCatalog, strategiesCatalog, sessionsCatalog); err != nil {
+		debugLog.Fatalf("Invalid catalog: %v", err)
+	}
+	debugLog.Printf("Loaded %d criteria groups, %d strategies and %d sessions.", len(optimizer.SelectableCombinations), len(optimizer.TradeStrategies), len(optimizer.TradingSessions))
+
 	config, err := db.FetchConfiguration(configID)
 	if err != nil {
 		debugLog.Fatalf("Failed to fetch configuration: %v", err)
 	}
-	allTrades, err := db.FetchAllTrades(instrument, config.Settings["dataSheetName"].(string))
+	summary := &optimizer.RunSummary{
+		Version:    version,
+		ConfigID:   config.ID,
+		ConfigHash: configHash(config.Settings),
+		JobID:      jobID,
+		Datasets:   []optimizer.DatasetSummary{},
+		Workers:    numWorkers,
+		StartedAt:  startTime,
+	}
+	if err := optimizer.ValidatePositionSizing(config.Settings); err != nil {
+		debugLog.Fatalf("Invalid position sizing: %v", err)
+	}
+	if err := optimizer.ValidateEntryDelayBuckets(config.Settings); err != nil {
+		debugLog.Fatalf("Invalid entry delay buckets: %v", err)
+	}
+	if err := optimizer.ValidateExclusiveCombinations(config.Settings); err != nil {
+		debugLog.Fatalf("Invalid combinations to test: %v", err)
+	}
+	newsEvents, err := loadNewsCalendar(db)
 	if err != nil {
-		debugLog.Fatalf("Failed to fetch trades: %v", err)
+		debugLog.Fatalf("Failed to load news calendar: %v", err)
+	}
+	holidayOverrides, err := loadHolidayOverrides()
+	if err != nil {
+		debugLog.Fatalf("Failed to load holiday calendar: %v", err)
 	}
-	debugLog.Printf("Fetched %d total trades.", len(allTrades))
 
 	// --- 3. Pre-Analysis and Job Generation ---
-	finalTrades, timeWindows, err := optimizer.PrepareTradesForAnalysis(allTrades, config.Settings)
-	if err != nil {
-		debugLog.Fatalf("Error preparing trades: %v", err)
+	// Every instrument and timeframe is loaded and prepared on its own; the combinations
+	// are generated once and evaluated against each of them.
+	var datasets []optimizer.Dataset
+	warnings := append(catalogWarnings, optimizer.ValidateStrategyConstraints(config.Settings)...)
+	var timeWindows []map[string]int
+	enabledCriteria := optimizer.BuildEnabledCriteria(config.Settings)
+	unavailableCriteria := 0
+	for _, instrument := range instruments {
+		for _, timeframe := range timeframes(config.Settings) {
+			tradeTable, err := db.FetchAllTrades(instrument, timeframe)
+			if err != nil {
+				debugLog.Fatalf("Failed to fetch trades: %v", err)
+			}
+			debugLog.Printf("Fetched %d total %s %s trades with %d columns.", tradeTable.Len(), instrument, timeframe, len(tradeTable.ColumnNames()))
+			if err := optimizer.ValidateStrategyColumns(tradeTable); err != nil {
+				debugLog.Fatalf("Invalid strategy catalog: %v", err)
+			}
+			warnings = append(warnings, optimizer.AddDerivedColumns(tradeTable, config.Settings, optimizer.DerivedInputs{
+				Instrument: instrument,
+				News:       newsEvents,
+				Holidays:   holidayOverrides,
+			})...)
+
+			var finalTrades []optimizer.Trade
+			finalTrades, timeWindows, err = optimizer.PrepareTradesForAnalysis(tradeTable.Trades(), config.Settings)
+			if err != nil {
+				debugLog.Fatalf("Error preparing trades: %v", err)
+			}
+			debugLog.Printf("Finished pre-filtering. %d %s %s trades remain for optimization.", len(finalTrades), instrument, timeframe)
+			summary.Datasets = append(summary.Datasets, optimizer.DatasetSummary{
+				Instrument:     instrument,
+				Timeframe:      timeframe,
+				LoadedTrades:   tradeTable.Len(),
+				PreparedTrades: len(finalTrades),
+			})
+
+			// Criteria on columns the trade data does not carry would be silently ignored by
+			// ApplyFilters while still multiplying the search space, so they are removed here.
+			var criteriaWarnings []string
+			enabledCriteria, criteriaWarnings = optimizer.ValidateEnabledCriteria(enabledCriteria, tradeTable)
+			warnings = append(warnings, criteriaWarnings...)
+			unavailableCriteria += len(criteriaWarnings)
+
+			if len(finalTrades) > 0 {
+				datasets = append(datasets, optimizer.Dataset{Instrument: instrument, Timeframe: timeframe, Trades: finalTrades})
+			}
+		}
+	}
+	for _, warning := range warnings {
+		debugLog.Printf("WARNING: %s", warning)
+	}
+	if reject, _ := config.Settings["rejectUnavailableCriteria"].(bool); reject && unavailableCriteria > 0 {
+		debugLog.Fatalf("Configuration enables %d unavailable criteria.", unavailableCriteria)
 	}
-	debugLog.Printf("Finished pre-filtering. %d trades remain for optimization.", len(finalTrades))
 
-	if len(finalTrades) == 0 {
-		outputEmptyResult()
+	if len(datasets) == 0 {
+		outputEmptyResult(db, config.Settings, instruments, warnings, summary)
 		return
 	}
 
-	enabledCriteria := optimizer.BuildEnabledCriteria(config.Settings)
+	var thresholdWarnings []string
+	enabledCriteria, thresholdWarnings = optimizer.ApplyThresholdSources(enabledCriteria, datasets, config.Settings)
+	for _, warning := range thresholdWarnings {
+		debugLog.Printf("WARNING: %s", warning)
+	}
+	warnings = append(warnings, thresholdWarnings...)
+	var groupColumns []string
+	if splitSetups, _ := config.Settings["splitSetups"].(bool); splitSetups {
+		// The shared combinations test every setup of any dataset; a setup a dataset lacks
+		// leaves too few trades there and is discarded for it.
+		var finalTrades []optimizer.Trade
+		for _, dataset := range datasets {
+			finalTrades = append(finalTrades, dataset.Trades...)
+		}
+		enabledCriteria = optimizer.SplitSetupCriteria(enabledCriteria, finalTrades)
+		groupColumns = append(groupColumns, "Setup")
+	}
+	if splitDirections, _ := config.Settings["splitDirections"].(bool); splitDirections {
+		enabledCriteria = optimizer.SplitDirectionCriteria(enabledCriteria)
+		groupColumns = append(groupColumns, "Direction")
+	}
 	timeShiftEnabled, _ := config.Settings["enableTimeShift"].(bool)
 	totalJobs := optimizer.CalculateTotalCombinations(enabledCriteria, timeWindows, timeShiftEnabled)
 	debugLog.Printf("Calculated total jobs to process: %d", totalJobs)
+	summary.TotalCombinations = totalJobs
 	//baseCombinations := optimizer.GenerateCombinations(enabledCriteria)
 	//combinationsJSON, _ := json.Marshal(baseCombinations)
 	//log.Println(string(combinationsJSON))
@@ -84,7 +195,7 @@ func main() {
 
 	var processWg, genWg sync.WaitGroup // Use two separate WaitGroups
 
-	inputData := &optimizer.InputData{Config: config, Trades: finalTrades}
+	inputData := &optimizer.InputData{Config: config, Datasets: datasets, Rejections: &optimizer.RejectionCollector{}}
 
 	for w := 1; w <= numWorkers; w++ {
 		processWg.Add(1)
@@ -145,21 +256,58 @@ func main() {
 
 	// --- 6. Finalize and Output Results ---
 	// The `rawResults` slice is now fully populated.
-	finalOutput := optimizer.ProcessFinalResults(rawResults)
+	summary.EvaluatedCombinations = atomic.LoadUint64(&processedCounter)
+	summary.RawResults = len(rawResults)
+	summary.Rejections = inputData.Rejections.Counts()
+	debugLog.Printf("Rejected combinations: %v", summary.Rejections.Combinations)
+	for strategy, reasons := range summary.Rejections.Strategies {
+		debugLog.Printf("Strategy %s rejected by constraints: %v", strategy, reasons)
+	}
+
+	maxOverlap, _ := config.Settings["maxOverlap"].(float64)
+	finalOutput := optimizer.ProcessFinalResultsPerDataset(rawResults, datasets, groupColumns, maxOverlap)
 	debugLog.Printf("Processing complete. Found top results for %d strategies.", len(finalOutput)) // It might not be len(topResultsPerStrategy) anymore
+	summary.FinalResults = len(finalOutput)
+	optimizer.AttachEntryDelayHistograms(finalOutput, datasets, config.Settings)
+	optimizer.AttachTimeframeComparisons(finalOutput, datasets, config.Settings)
+	overlap := optimizer.AttachFingerprints(finalOutput, datasets)
 
-	outputJSON, err := json.Marshal(finalOutput)
+	portfolios, err := optimizer.BuildPortfolios(finalOutput, datasets, config.Settings)
 	if err != nil {
-		debugLog.Fatalf("Error marshaling final output JSON: %v", err)
+		warnings = append(warnings, "Portfolio stage skipped: "+err.Error())
 	}
-	fmt.Print(string(outputJSON))
+
+	var tradeDetails []optimizer.TradeDetails
+	var tradeDetailsFile string
+	switch mode, _ := config.Settings["tradeDetails"].(string); mode {
+	case "":
+	case "attach":
+		optimizer.AttachTradeDetails(finalOutput, optimizer.BuildTradeDetails(finalOutput, datasets, config.Settings))
+	case "file":
+		tradeDetails = optimizer.BuildTradeDetails(finalOutput, datasets, config.Settings)
+		if databaseResultSink() {
+			// Stored with the result row instead.
+			break
+		}
+		path := tradeDetailsPath(jobID)
+		if err := optimizer.WriteTradeDetailsFile(path, tradeDetails); err != nil {
+			warnings = append(warnings, "Trade details skipped: "+err.Error())
+		} else {
+			debugLog.Printf("Trade details written to %s.", path)
+			tradeDetailsFile = path
+		}
+	default:
+		warnings = append(warnings, fmt.Sprintf("Trade details skipped: unknown mode %q.", mode))
+	}
+
+	writeOutput(db, config.Settings, instruments, optimizer.RunOutput{Warnings: warnings, Results: finalOutput, Portfolios: portfolios, Overlap: overlap, TradeDetailsFile: tradeDetailsFile, TradeDetails: tradeDetails, Summary: summary})
 }
 
 // --- Main Helper Functions ---
 
-func parseArgsAndSetup() (instrument string, configID int, jobID string, numWorkers int) {
+func parseArgsAndSetup() (instruments []string, configID int, jobID string, numWorkers int) {
 	if len(os.Args) < 4 {
-		log.Fatal("Usage: ./optimizer <instrument> <configID> <jobId> [priority]")
+		log.Fatal("Usage: ./optimizer <instrument[,instrument...]> <configID> <jobId> [priority]")
 	}
 	var err error
 	configID, err = strconv.Atoi(os.Args[2])
@@ -167,10 +315,19 @@ func parseArgsAndSetup() (instrument string, configID int, jobID string, numWork
 		log.Fatalf("Invalid Config ID: %s", os.Args[2])
 	}
 
-	instrument = os.Args[1]
-	if instrument == "" {
+	for _, instrument := range strings.Split(os.Args[1], ",") {
+		if instrument = strings.TrimSpace(instrument); instrument != "" {
+			instruments = append(instruments, instrument)
+		}
+	}
+	if len(instruments) == 0 {
 		log.Fatalf("No instrument given")
 	}
+	// The result row is stored under the joined instruments; checking them now keeps a long
+	// run from failing at the insert after all the work is done.
+	if joined := strings.Join(instruments, ","); len(joined) > database.InstrumentColumnLength {
+		log.Fatalf("Instruments %q exceed the %d characters a result can be stored under", joined, database.InstrumentColumnLength)
+	}
 
 	jobID = os.Args[3]
 
@@ -184,6 +341,24 @@ func parseArgsAndSetup() (instrument string, configID int, jobID string, numWork
 	return
 }
 
+// timeframes returns the timeframes to load: the "dataSheetNames" list if given, else
+// the single "dataSheetName". This is the only place a run is given several timeframes;
+// the timeframe comparison and cross ranking work on whatever datasets it loads.
+func timeframes(settings map[string]interface{}) []string {
+	var names []string
+	if list, ok := settings["dataSheetNames"].([]interface{}); ok {
+		for _, name := range list {
+			if s, ok := name.(string); ok && s != "" {
+				names = append(names, s)
+			}
+		}
+	}
+	if len(names) == 0 {
+		names = append(names, settings["dataSheetName"].(string))
+	}
+	return names
+}
+
 func getEnvVars() (dbURL, redisURL string) {
 	dbURL = os.Getenv("DATABASE_URL")
 	redisURL = os.Getenv("REDIS_URL")
@@ -204,7 +379,140 @@ func determineGeneratorCount(numBaseCombos int) int {
 	return numGen
 }
 
-func outputEmptyResult() {
+// loadNewsCalendar reads the news calendar from the file named by the NEWS_CALENDAR_FILE
+// environment variable, or else from the news_event table. The file is deployment
+// configuration; settings are user-editable and cannot name one.
+func loadNewsCalendar(db *database.DB) ([]optimizer.NewsEvent, error) {
+	if path := os.Getenv("NEWS_CALENDAR_FILE"); path != "" {
+		return optimizer.LoadNewsCalendarFile(path)
+	}
+	return db.FetchNewsEvents()
+}
+
+// loadHolidayOverrides reads the holiday overrides from the file named by the
+// HOLIDAY_CALENDAR_FILE environment variable, like the news calendar.
+func loadHolidayOverrides() ([]optimizer.HolidayOverride, error) {
+	path := os.Getenv("HOLIDAY_CALENDAR_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return optimizer.LoadHolidayFile(path)
+}
+
+// tradeDetailsPath is the side file for the trade details, named after the job in
+// TRADE_DETAILS_DIR or the temporary directory. The orchestrator stores it with the result
+// and removes it.
+func tradeDetailsPath(jobID string) string {
+	dir := os.Getenv("TRADE_DETAILS_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "trade-details-"+filepath.Base(jobID)+".json")
+}
+
+// databaseResultSink reports whether the run output is stored by the optimizer itself,
+// with the OPTIMIZER_RESULT_SINK environment variable set to "database".
+func databaseResultSink() bool {
+	return os.Getenv("OPTIMIZER_RESULT_SINK") == "database"
+}
+
+func outputEmptyResult(db *database.DB, settings map[string]interface{}, instruments []string, warnings []string, summary *optimizer.RunSummary) {
 	debugLog.Println("No trades remaining. Exiting successfully.")
-	fmt.Print("[]")
+	writeOutput(db, settings, instruments, optimizer.RunOutput{Warnings: warnings, Results: []optimizer.Result{}, Summary: summary})
+}
+
+// configHash identifies the settings a run used; encoding/json sorts map keys, so equal
+// settings hash equally. It covers the settings only, not the criteria, strategy and session
+// catalogs, so runs with the same settings on different catalogs report the same hash.
+func configHash(settings map[string]interface{}) string {
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(settingsJSON)
+	return hex.EncodeToString(sum[:8])
+}
+
+// writeOutput delivers the run output. With the OPTIMIZER_RESULT_SINK environment variable
+// set to "database", it is stored in the optimization_result table and only the new row id
+// is printed. Otherwise the RunOutput envelope is written to stdout; for orchestrators that
+// still expect the bare results array, only the results are printed with bareOutput.
+func writeOutput(db *database.DB, settings map[string]interface{}, instruments []string, runOutput optimizer.RunOutput) {
+	if runOutput.Summary != nil {
+		runOutput.Summary.DurationSeconds = time.Since(runOutput.Summary.StartedAt).Seconds()
+	}
+	if runOutput.Warnings == nil {
+		runOutput.Warnings = []string{}
+	}
+
+	if databaseResultSink() {
+		id, err := storeOutput(db, settings, instruments, runOutput)
+		if err != nil {
+			debugLog.Fatalf("Error storing the results: %v", err)
+		}
+		debugLog.Printf("Stored %d results as optimization result %d.", len(runOutput.Results), id)
+		fmt.Print(id)
+		return
+	}
+
+	var output interface{} = runOutput
+	if bareOutput(settings) {
+		output = runOutput.Results
+		if len(runOutput.Portfolios) > 0 {
+			debugLog.Println("WARNING: Portfolios are not written with the bare results output.")
+		}
+	}
+
+	outputJSON, err := json.Marshal(output)
+	if err != nil {
+		debugLog.Fatalf("Error marshaling final output JSON: %v", err)
+	}
+	fmt.Print(string(outputJSON))
+}
+
+// bareOutput reports whether only the results array is delivered, with the "outputEnvelope"
+// setting false or the OPTIMIZER_BARE_OUTPUT environment variable "true".
+func bareOutput(settings map[string]interface{}) bool {
+	envelope, ok := settings["outputEnvelope"].(bool)
+	bare, _ := strconv.ParseBool(os.Getenv("OPTIMIZER_BARE_OUTPUT"))
+	return bare || (ok && !envelope)
+}
+
+// storedSummary is the RunOutput envelope without its results, as the Node orchestrator
+// stores it in the summary column. It must list the same fields as RunOutput.
+type storedSummary struct {
+	Warnings   []string              `json:"warnings"`
+	Portfolios []optimizer.Portfolio `json:"portfolios,omitempty"`
+	Overlap    [][]float64           `json:"overlap,omitempty"`
+	Summary    *optimizer.RunSummary `json:"summary,omitempty"`
+}
+
+// storeOutput inserts the run output as the Node orchestrator stores it: the results array,
+// and the rest of the envelope as summary, which is NULL with bare output. The output must
+// carry its run summary.
+func storeOutput(db *database.DB, settings map[string]interface{}, instruments []string, runOutput optimizer.RunOutput) (int, error) {
+	resultsJSON, err := json.Marshal(runOutput.Results)
+	if err != nil {
+		return 0, fmt.Errorf("could not marshal results: %w", err)
+	}
+	var summaryJSON []byte
+	if !bareOutput(settings) {
+		summaryJSON, err = json.Marshal(storedSummary{
+			Warnings:   runOutput.Warnings,
+			Portfolios: runOutput.Portfolios,
+			Overlap:    runOutput.Overlap,
+			Summary:    runOutput.Summary,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("could not marshal run summary: %w", err)
+		}
+	}
+	var tradeDetailsJSON []byte
+	if runOutput.TradeDetails != nil {
+		tradeDetailsJSON, err = json.Marshal(runOutput.TradeDetails)
+		if err != nil {
+			return 0, fmt.Errorf("could not marshal trade details: %w", err)
+		}
+	}
+	return db.InsertOptimizationResult(strings.Join(instruments, ","), runOutput.Summary.ConfigID, resultsJSON, summaryJSON, tradeDetailsJSON, runOutput.Summary.StartedAt)
 }
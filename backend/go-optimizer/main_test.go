@@ -0,0 +1,47 @@
+package main
+
+import (
+	"go-optimizer/optimizer"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStoredSummaryMatchesRunOutput(t *testing.T) {
+	// Results are stored in their own column, and the trade details file is read by Node
+	// before the summary is stored.
+	skipped := map[string]bool{"results": true, "tradeDetailsFile": true, "-": true}
+	jsonNames := func(value interface{}) map[string]bool {
+		names := make(map[string]bool)
+		typ := reflect.TypeOf(value)
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if !skipped[name] {
+				names[name] = true
+			}
+		}
+		return names
+	}
+	if got, want := jsonNames(storedSummary{}), jsonNames(optimizer.RunOutput{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("storedSummary fields %v, RunOutput envelope fields %v", got, want)
+	}
+}
+
+func TestBareOutput(t *testing.T) {
+	tests := []struct {
+		settings map[string]interface{}
+		env      string
+		bare     bool
+	}{
+		{map[string]interface{}{}, "", false},
+		{map[string]interface{}{"outputEnvelope": true}, "", false},
+		{map[string]interface{}{"outputEnvelope": false}, "", true},
+		{map[string]interface{}{"outputEnvelope": true}, "true", true},
+	}
+	for _, tt := range tests {
+		t.Setenv("OPTIMIZER_BARE_OUTPUT", tt.env)
+		if got := bareOutput(tt.settings); got != tt.bare {
+			t.Errorf("bareOutput(%v) with OPTIMIZER_BARE_OUTPUT=%q = %v, want %v", tt.settings, tt.env, got, tt.bare)
+		}
+	}
+}
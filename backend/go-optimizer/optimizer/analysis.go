@@ -7,18 +7,35 @@ import (
 	"log"
 	"math"
 	"os"
-	"reflect"
 	"sort"
-	"strings"
 )
 
 var debugLog = log.New(os.Stderr, "[Go-Optimizer-Debug] ", log.Ltime)
 
-// reflection-based Helper to get a field value from a Trade struct.
-func getField(v *Trade, field string) reflect.Value {
-	r := reflect.ValueOf(v)
-	f := reflect.Indirect(r).FieldByName(field)
-	return f
+// exactValueMatches compares a column value with an exact condition. Numeric conditions
+// match integer and float columns alike, since JSON catalogs deliver every number as float64.
+// Conditions of any other type do not restrict the trade.
+func exactValueMatches(column *TradeColumn, row int, condition interface{}) bool {
+	switch cond := condition.(type) {
+	case bool:
+		return column.Kind == BoolColumn && column.Bools[row] == cond
+	case string:
+		return column.Kind == StringColumn && column.Strings[row] == cond
+	case int:
+		val, ok := column.Number(row)
+		return ok && val == float64(cond)
+	case float64:
+		val, ok := column.Number(row)
+		return ok && val == cond
+	case []interface{}: // Any of several values, e.g. the setups of a multi-setup run
+		for _, value := range cond {
+			if exactValueMatches(column, row, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
 }
 
 // ApplyFilters takes a list of trades and a combination, returning the trades that match.
@@ -31,22 +48,43 @@ func ApplyFilters(trades []Trade, combo Combination) ([]Trade, bool, float64) {
 		ltaCombination = true
 	}
 
+	// The combination's keys are resolved once, not per trade.
+	type comboCondition struct {
+		key       string
+		condition interface{}
+		column    *columnResolver
+	}
+	conditions := make([]comboCondition, 0, len(combo))
+	for key, condition := range combo {
+		switch key {
+		case "CandleSizeTPRatio":
+			candleSizeTpRatio = condition.(map[string]float64)["max"]
+		case "TimeFilter":
+			conditions = append(conditions, comboCondition{key: key, condition: condition})
+		case sessionCriterion:
+			// The session name selects its Session_<name> flag column.
+			var column *columnResolver
+			if name, ok := condition.(string); ok {
+				column = &columnResolver{buyName: sessionColumnPrefix + name, sellName: sessionColumnPrefix + name}
+			}
+			conditions = append(conditions, comboCondition{key: key, condition: condition, column: column})
+		default:
+			conditions = append(conditions, comboCondition{key: key, condition: condition, column: newColumnResolver(key)})
+		}
+	}
+
 tradeLoop:
 	for _, trade := range trades {
-		for key, condition := range combo {
+		for _, c := range conditions {
+			key, condition := c.key, c.condition
 
-			if key == "CandleSizeTPRatio" {
-				conditionMap := condition.(map[string]float64)
-				candleSizeTpRatio = conditionMap["max"]
-				continue
-			}
 			if key == "TimeFilter" {
 				timeConditionMap, ok := condition.(map[string]int)
 				if !ok {
 					continue tradeLoop
 				}
 
-				tradeTime, err := utils.TimeToMinutes(trade.Time)
+				tradeTime, err := tradeFilterMinutes(trade)
 				if err != nil {
 					continue tradeLoop
 				}
@@ -62,34 +100,34 @@ tradeLoop:
 				}
 				continue
 			}
-			var tradeValue reflect.Value
-			if strings.Contains(key, "|") {
-				parts := strings.Split(key, "|")
-				if trade.Direction == "BUY" {
-					tradeValue = getField(&trade, parts[0])
-				} else {
-					tradeValue = getField(&trade, parts[1])
+			if key == sessionCriterion {
+				if c.column == nil {
+					continue
+				}
+				if column, _ := c.column.resolve(trade); column == nil || column.Kind != BoolColumn || !column.Bools[trade.Row] {
+					continue tradeLoop
 				}
-			} else {
-				tradeValue = getField(&trade, key)
+				continue
 			}
-
-			if !tradeValue.IsValid() {
+			column, negate := c.column.resolve(trade)
+			if column == nil {
 				continue
 			}
 			switch cond := condition.(type) {
 			case bool:
-				if tradeValue.Bool() != cond {
+				if column.Kind != BoolColumn || (column.Bools[trade.Row] != negate) != cond {
+					continue tradeLoop
+				}
+			case string, int, float64: // For exact values such as Setup or Day_Of_Week
+				if !exactValueMatches(column, trade.Row, cond) {
 					continue tradeLoop
 				}
 			case map[string]float64: // For numeric ranges
 				min, minOk := cond["min"]
 				max, maxOk := cond["max"]
-				var val float64
-				if tradeValue.CanInt() {
-					val = float64(tradeValue.Int())
-				} else if tradeValue.CanFloat() {
-					val = tradeValue.Float()
+				val, _ := column.Number(trade.Row)
+				if negate {
+					val = -val
 				}
 				if minOk && val < min {
 					continue tradeLoop
@@ -97,6 +135,12 @@ tradeLoop:
 				if maxOk && val > max {
 					continue tradeLoop
 				}
+				// Exclusion bands drop trades that fall inside [excludeMin, excludeMax].
+				excludeMin, exMinOk := cond["excludeMin"]
+				excludeMax, exMaxOk := cond["excludeMax"]
+				if exMinOk && exMaxOk && val >= excludeMin && val <= excludeMax {
+					continue tradeLoop
+				}
 			}
 		}
 		filteredTrades = append(filteredTrades, trade)
@@ -104,98 +148,175 @@ tradeLoop:
 	return filteredTrades, ltaCombination, candleSizeTpRatio
 }
 
+// metricsAccumulator sums the outcomes of a strategy's trades, sized by the run's
+// position sizing model, and follows their equity curve.
+type metricsAccumulator struct {
+	wonTrades, trades                           int
+	grossProfit, grossLoss, equity, peak, maxDD float64
+}
+
+// add books a trade. won is decided on the trade's pips, not on its sized result, so a
+// trade sized to nothing keeps its outcome: a simple strategy wins when its target was
+// hit, a composite one when its legs made pips in total. A composite closed at break-even
+// without an offset made none and counts as a loss.
+func (a *metricsAccumulator) add(pnl float64, won bool) {
+	a.trades++
+	if won {
+		a.wonTrades++
+	}
+	if pnl > 0 {
+		a.grossProfit += pnl
+	} else {
+		a.grossLoss -= pnl
+	}
+	a.equity += pnl
+	a.peak = math.Max(a.peak, a.equity)
+	a.maxDD = math.Max(a.maxDD, a.peak-a.equity)
+}
+
+func (a metricsAccumulator) metrics() StrategyMetrics {
+	winRate := 0.0
+	if a.trades > 0 {
+		winRate = float64(a.wonTrades) / float64(a.trades)
+	}
+
+	profitFactor := 0.0
+	if a.grossLoss > 0 {
+		profitFactor = a.grossProfit / a.grossLoss
+	} else if a.grossProfit > 0 {
+		profitFactor = math.Inf(1)
+	}
+
+	return StrategyMetrics{
+		WinRate:                 winRate,
+		ProfitFactor:            profitFactor,
+		TotalTradesThisStrategy: a.trades,
+		NetProfit:               a.grossProfit - a.grossLoss,
+		MaxDrawdown:             a.maxDD,
+	}
+}
+
+// outcomeLimits are the run settings that exclude single trades from a strategy.
+type outcomeLimits struct {
+	minSLToTPRatio, maxTPToSLRatio, maxCandleSizeTPRatio float64
+}
+
+func newOutcomeLimits(settings map[string]interface{}, maxCandleSizeTPRatio float64) outcomeLimits {
+	minSLToTPRatio, _ := settings["minSLToTPRatio"].(float64)
+	maxTPToSLRatio, _ := settings["maxTPToSLRatio"].(float64)
+	return outcomeLimits{minSLToTPRatio, maxTPToSLRatio, maxCandleSizeTPRatio}
+}
+
+// allow reports whether a trade with the given target, the blended one of a composite
+// strategy, is within the TP/SL ratio and candle size limits.
+func (limits outcomeLimits) allow(trade Trade, tpPips, slPips float64) bool {
+	ratio := tpPips / slPips
+	if limits.minSLToTPRatio != 0 && ratio < limits.minSLToTPRatio {
+		return false
+	}
+	if limits.maxTPToSLRatio != 0 && ratio > limits.maxTPToSLRatio {
+		return false
+	}
+	if limits.maxCandleSizeTPRatio != 0.0 {
+		candleSizeTPRatio := tpPips / trade.Float("Candle_Size")
+		if candleSizeTPRatio > limits.maxCandleSizeTPRatio {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluateTrade returns the pips the trade made under the strategy, negative for a loss,
+// and its SL distance in pips. ok is false if the strategy does not count the trade. Setup
+// applicability is checked by the caller.
+func evaluateTrade(trade Trade, strategy Strategy, limits outcomeLimits) (pips, slPips float64, ok bool) {
+	slPips = trade.Float(strategy.SLPipsColumn)
+	if slPips == 0 {
+		return 0, 0, false
+	}
+
+	if len(strategy.Legs) > 1 {
+		pips, ok = compositeOutcome(trade, strategy.Legs, strategy.BreakEvenLeg, strategy.BreakEvenOffsetPips, slPips, limits)
+		return pips, slPips, ok
+	}
+	isWin, tpPips, ok := strategy.Legs[0].target(trade, slPips)
+	if !ok || !limits.allow(trade, tpPips, slPips) {
+		return 0, 0, false
+	}
+	if isWin {
+		return tpPips, slPips, true
+	}
+	return -slPips, slPips, true
+}
+
 // CalculateMetrics computes all strategy metrics for a given set of trades.
 func CalculateMetrics(trades []Trade, ltaCombination bool, settings map[string]interface{}, maxCandleSizeTPRatio float64) map[string]StrategyMetrics {
 	results := make(map[string]StrategyMetrics)
 	allSetupsFailed := true
 
-	minSLToTPRatio, _ := settings["minSLToTPRatio"].(float64)
-	maxTPToSLRatio, _ := settings["maxTPToSLRatio"].(float64)
+	limits := newOutcomeLimits(settings, maxCandleSizeTPRatio)
 	minWinRate, _ := settings["minWinRate"].(float64)
 	minProfitFactor, _ := settings["minProfitFactor"].(float64)
-	isS2Setup := IsS2Setup(settings)
+	sizing := newPositionSizing(settings)
 
-	for _, strategy := range TradeStrategies {
-		name := strategy["name"].(string)
-		isLTA := strategy["lta"].(bool)
-		isS2 := strategy["s2"].(bool)
+	for _, strategy := range Strategies {
+		name := strategy.Name
 
-		wonTrades, grossProfit, grossLoss, strategyTrades := 0, 0.0, 0.0, 0
+		var all, buy, sell metricsAccumulator
+		// Per-setup metrics are only reported for strategies that can take several setups.
+		var perSetup map[string]*metricsAccumulator
+		splitSetups := len(strategy.Setups) != 1
+		account := sizing.newAccount()
 
-		if (ltaCombination && isLTA) || (!ltaCombination && !isLTA) {
+		if strategy.LTA == ltaCombination {
 			for _, trade := range trades {
-				isWin := getField(&trade, strategy["winColumn"].(string)).Bool()
-				tpPips := getField(&trade, strategy["tpPipsColumn"].(string)).Float()
-				slPips := getField(&trade, strategy["slPipsColumn"].(string)).Float()
-				isBreakout := false
-				if strategy["rangeBreakoutColumn"] != "" {
-					isBreakout = getField(&trade, strategy["rangeBreakoutColumn"].(string)).Bool()
-					if tpPips == 0 && !isBreakout {
-						continue
-					}
+				if account.blown() {
+					break
 				}
-
-				if slPips == 0 {
+				setup := trade.String("Setup")
+				if !setupApplies(setup, strategy.Setups, strategy.ExcludedSetups) {
 					continue
 				}
-				if tpPips == 0 {
-					tpPips = slPips
-				}
-
-				ratio := tpPips / slPips
-				if minSLToTPRatio != 0 && ratio < minSLToTPRatio {
+				pips, slPips, ok := evaluateTrade(trade, strategy, limits)
+				if !ok {
 					continue
 				}
-				if maxTPToSLRatio != 0 && ratio > maxTPToSLRatio {
-					continue
+				pnl, won := account.settle(trade, pips, slPips), pips > 0
+
+				all.add(pnl, won)
+				if trade.String("Direction") == "BUY" {
+					buy.add(pnl, won)
+				} else {
+					sell.add(pnl, won)
 				}
-				if tpPips < 1.0 {
+				if !splitSetups {
 					continue
 				}
-
-				if maxCandleSizeTPRatio != 0.0 {
-					candleSizeTPRatio := tpPips / trade.Candle_Size
-					if candleSizeTPRatio > maxCandleSizeTPRatio {
-						continue
-					}
+				if perSetup == nil {
+					perSetup = make(map[string]*metricsAccumulator)
 				}
-
-				strategyTrades++
-				moneyPerPip := 100.0 / slPips
-				if isWin {
-					wonTrades++
-					grossProfit += tpPips * moneyPerPip
-				} else {
-					grossLoss += 100.0
+				if perSetup[setup] == nil {
+					perSetup[setup] = &metricsAccumulator{}
 				}
+				perSetup[setup].add(pnl, won)
 			}
 		}
-		if isS2Setup && !isS2 {
-			wonTrades, grossProfit, grossLoss, strategyTrades = 0, 0.0, 0.0, 0
-		}
 
-		winRate := 0.0
-		if strategyTrades > 0 {
-			winRate = float64(wonTrades) / float64(strategyTrades)
-		}
-
-		profitFactor := 0.0
-		if grossLoss > 0 {
-			profitFactor = grossProfit / grossLoss
-		} else if grossProfit > 0 {
-			profitFactor = math.Inf(1)
-		}
-
-		if (profitFactor >= minProfitFactor || profitFactor == math.Inf(1)) && (winRate*100 >= minWinRate) {
+		metrics := all.metrics()
+		if (metrics.ProfitFactor >= minProfitFactor || metrics.ProfitFactor == math.Inf(1)) && (metrics.WinRate*100 >= minWinRate) {
 			allSetupsFailed = false
 		}
 
-		results[name] = StrategyMetrics{
-			WinRate:                 winRate,
-			ProfitFactor:            profitFactor,
-			TotalTradesThisStrategy: strategyTrades,
-			NetProfit:               grossProfit - grossLoss,
+		buyMetrics, sellMetrics := buy.metrics(), sell.metrics()
+		metrics.Buy, metrics.Sell = &buyMetrics, &sellMetrics
+		if len(perSetup) > 1 {
+			metrics.PerSetup = make(map[string]*StrategyMetrics)
+			for setup, acc := range perSetup {
+				setupMetrics := acc.metrics()
+				metrics.PerSetup[setup] = &setupMetrics
+			}
 		}
+		results[name] = metrics
 	}
 	if allSetupsFailed {
 		return nil
@@ -232,51 +353,72 @@ func CalculateCompositeScore(metrics StrategyMetrics, weights map[string]interfa
 }
 
 // --- The main pre-filtering logic ---
-func ApplyPredefinedFilters(trades []Trade, filters []interface{}) []Trade {
+func ApplyPredefinedFilters(trades []Trade, filters []interface{}) ([]Trade, error) {
 	if len(filters) == 0 {
-		return trades
+		return trades, nil
+	}
+
+	// Date ranges are resolved once, "lastMonths" depends on the whole trade set.
+	dateRanges := make(map[int][2]int)
+	for i, filterInterface := range filters {
+		filter := filterInterface.(map[string]interface{})
+		if filter["type"] == "dateRange" {
+			condition, _ := filter["condition"].(map[string]interface{})
+			fromKey, toKey, err := resolveDateRange(condition, trades)
+			if err != nil {
+				return nil, fmt.Errorf("invalid dateRange filter: %w", err)
+			}
+			debugLog.Printf("Restricting trades to dates %d - %d.", fromKey, toKey)
+			dateRanges[i] = [2]int{fromKey, toKey}
+		}
 	}
 
 	var filteredTrades []Trade
 
 tradeLoop:
 	for i := range trades {
-		trade := &trades[i] // Use pointer to avoid copying
-		entered := trade.Entered
-		canceled_after_candles := trade.Canceled_After_Candles
+		trade := trades[i]
+		entered := trade.Bool("Entered")
+		canceled_after_candles := trade.Int("Canceled_After_Candles")
 		if !entered || canceled_after_candles > 0 {
 			continue
 		}
 
 		// A trade must pass ALL filters to be included
-		for _, filterInterface := range filters {
+		for filterIndex, filterInterface := range filters {
 			filter := filterInterface.(map[string]interface{})
 			filterType := filter["type"].(string)
 
 			if filterType == "exact" {
 				columnHeader := filter["columnHeader"].(string)
 				condition := filter["condition"]
-				tradeValue := getField(trade, columnHeader)
+				column := trade.Column(columnHeader)
 
-				if !tradeValue.IsValid() {
+				if column == nil {
 					continue
 				}
 
 				// Compare based on type
-				switch cond := condition.(type) {
-				case bool:
-					if tradeValue.Bool() != cond {
-						continue tradeLoop
-					}
-				case string:
-					if tradeValue.String() != cond {
-						continue tradeLoop
-					}
+				if !exactValueMatches(column, trade.Row, condition) {
+					continue tradeLoop
+				}
+			} else if filterType == "dateRange" {
+				dateKey := trade.Int("Date_Key")
+				if dateKey < dateRanges[filterIndex][0] || dateKey > dateRanges[filterIndex][1] {
+					continue tradeLoop
+				}
+			} else if filterType == "holidays" {
+				// Drops holidays, half days and the Christmas period; "excludeAdjacent"
+				// also drops the trading days next to them.
+				if trade.Bool("Is_Holiday_Period") {
+					continue tradeLoop
+				}
+				condition, _ := filter["condition"].(map[string]interface{})
+				if excludeAdjacent, _ := condition["excludeAdjacent"].(bool); excludeAdjacent && trade.Bool("Is_Holiday_Adjacent") {
+					continue tradeLoop
 				}
 			} else if filterType == "timeRange" {
-				// The trade's Time field is not in the Trade struct, needs to be added if this filter is used.
-				// For now, this is a placeholder. You would add `Time string` to your Trade struct.
-				tradeTime, err := utils.TimeToMinutes(trade.Time)
+				tradeTime, err := tradeFilterMinutes(trade)
 				if err != nil {
 					continue tradeLoop
 				}
@@ -300,9 +442,9 @@ tradeLoop:
 			}
 		}
 		// If the trade survived all filters, add it to the result slice
-		filteredTrades = append(filteredTrades, *trade)
+		filteredTrades = append(filteredTrades, trade)
 	}
-	return filteredTrades
+	return filteredTrades, nil
 }
 
 // extractTimeWindowFromFilters is a private helper to find a time range filter.
@@ -379,97 +521,115 @@ func PrepareTradesForAnalysis(allTrades []Trade, settings map[string]interface{}
 	}
 
 	// Apply the remaining predefined filters
-	var filteredTrades []Trade
-	filteredTrades = ApplyPredefinedFilters(allTrades, predefinedFilters)
+	filteredTrades, err := ApplyPredefinedFilters(allTrades, predefinedFilters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Compounding position sizes depend on the order of the trades.
+	if newPositionSizing(settings).compounds() {
+		sort.SliceStable(filteredTrades, func(i, j int) bool {
+			return tradeTimestamp(filteredTrades[i]) < tradeTimestamp(filteredTrades[j])
+		})
+	}
 	return filteredTrades, timeWindowVariations, nil
 }
 
-// ProcessFinalResults sorts and filters the raw results from workers to get the top N for each strategy.
-func ProcessFinalResultsSimple(rawResults []Result) []Result {
-	if len(rawResults) == 0 {
-		return []Result{}
+// extractRangeFromCombo is a small, safe helper to get the numeric range filter of a key in a combination.
+func extractRangeFromCombo(combo Combination, key string) (map[string]float64, bool) {
+	// 1. Check if the key (e.g., "Breakout_Distance") exists in the combination.
+	val, ok := combo[key]
+	if !ok {
+		return nil, false
 	}
 
-	topResultsPerStrategy := make(map[string][]Result)
-
-	for _, strategy := range TradeStrategies {
-		strategyName := strategy["name"].(string)
-		var relevantResults []Result
-		for _, res := range rawResults {
-			if score, ok := res.StrategyScores[strategyName]; ok && !math.IsInf(score, 0) && score > 0 {
-				relevantResults = append(relevantResults, res)
-			}
-		}
-
-		// Sort relevant results by this strategy's specific score, descending
-		sort.Slice(relevantResults, func(i, j int) bool {
-			scoreI := relevantResults[i].StrategyScores[strategyName]
-			scoreJ := relevantResults[j].StrategyScores[strategyName]
-			return scoreI > scoreJ
-		})
+	// 2. Safely assert the type to a map representing the numeric range.
+	rangeMap, ok := val.(map[string]float64)
+	return rangeMap, ok
+}
 
-		// Keep only the top 10
-		if len(relevantResults) > 10 {
-			relevantResults = relevantResults[:10]
-		}
-		if len(relevantResults) > 0 {
-			topResultsPerStrategy[strategyName] = relevantResults
-		}
+// compareRangeWidth compares the range filters of a key in two combinations.
+// It returns a positive number if a accepts the wider range, a negative number if b does,
+// and 0 if they are equal or cannot be compared. Open ends count as infinitely wide; a
+// higher upper bound wins first, then a lower lower bound. For exclusion bands the
+// narrower excluded band is the wider filter.
+func compareRangeWidth(a, b Combination, key string) int {
+	rangeA, okA := extractRangeFromCombo(a, key)
+	rangeB, okB := extractRangeFromCombo(b, key)
+	if !okA || !okB {
+		return 0
 	}
 
-	// Flatten the map into a final list for output
-	var endResults []Result
-	for _, results := range topResultsPerStrategy {
-		endResults = append(endResults, results...)
+	_, excludeA := rangeA["excludeMin"]
+	_, excludeB := rangeB["excludeMin"]
+	if excludeA != excludeB {
+		return 0
+	}
+	if excludeA {
+		widthA := rangeA["excludeMax"] - rangeA["excludeMin"]
+		widthB := rangeB["excludeMax"] - rangeB["excludeMin"]
+		return compareFloats(widthB, widthA)
 	}
 
-	return endResults
+	if c := compareFloats(rangeBound(rangeA, "max", math.Inf(1)), rangeBound(rangeB, "max", math.Inf(1))); c != 0 {
+		return c
+	}
+	return compareFloats(rangeBound(rangeB, "min", math.Inf(-1)), rangeBound(rangeA, "min", math.Inf(-1)))
 }
 
-// extractMaxFromCombo is a small, safe helper to get the 'max' value from a numeric range filter in a combination.
-func extractMaxFromCombo(combo Combination, key string) (float64, bool) {
-	// 1. Check if the key (e.g., "Breakout_Distance") exists in the combination.
-	val, ok := combo[key]
-	if !ok {
-		return 0, false
+func rangeBound(rangeMap map[string]float64, key string, open float64) float64 {
+	if v, ok := rangeMap[key]; ok {
+		return v
 	}
+	return open
+}
 
-	// 2. Safely assert the type to a map representing the numeric range.
-	rangeMap, ok := val.(map[string]float64)
-	if !ok {
-		return 0, false
+func compareFloats(a, b float64) int {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return -1
 	}
+	return 0
+}
 
-	// 3. Get the 'max' value from that map.
-	maxVal, ok := rangeMap["max"]
-	if !ok {
-		// This handles cases where there might be only a "min"
-		return 0, false
+// compareTieBreakers applies the tieBreakerKeys policy: the first key on which the
+// combinations differ decides, and the wider range ranks higher.
+func compareTieBreakers(a, b Combination) int {
+	for _, key := range tieBreakerKeys {
+		if c := compareRangeWidth(a, b, key); c != 0 {
+			return c
+		}
 	}
+	return 0
+}
 
-	return maxVal, true
+// Define the order of importance for tie-breaking.
+// We will prioritize the widest range of these keys in this order.
+var tieBreakerKeys = []string{
+	"Breakout_Distance",
+	"Entry_Distance",
+	"Candle_Size",
+	"Breakout_Candle_Count",
 }
 
-// --- MODIFIED FUNCTION ---
-// ProcessFinalResults sorts and filters the raw results to get the top N for each strategy.
-func ProcessFinalResults(rawResults []Result) []Result {
+// processFinalResults sorts and filters the raw results to get the top N for each strategy.
+// With an overlap filter, the top N of each strategy skips candidates sharing too many
+// trades with a better-ranked one.
+func processFinalResults(rawResults []Result, overlap *overlapFilter) []Result {
 	if len(rawResults) == 0 {
 		return []Result{}
 	}
 
-	// Define the order of importance for tie-breaking.
-	// We will prioritize maximizing the 'max' value of these keys in this order.
-	var tieBreakerKeys = []string{
-		"Breakout_Distance",
-		"Entry_Distance",
-		"Candle_Size",
-		"Breakout_Candle_Count",
-	}
+	// Combinations selecting the exact same trades are scored identically,
+	// so keep a single representative per class before ranking.
+	rawResults = CollapseEquivalentResults(rawResults)
 
 	topResultsPerStrategy := make(map[string][]Result)
 
-	for _, strategy := range TradeStrategies {
-		strategyName := strategy["name"].(string)
+	for _, strategy := range Strategies {
+		strategyName := strategy.Name
 		var relevantResults []Result
 		for _, res := range rawResults {
 			if score, ok := res.StrategyScores[strategyName]; ok && !math.IsInf(score, 0) && score > 0 {
@@ -490,17 +650,9 @@ func ProcessFinalResults(rawResults []Result) []Result {
 			}
 
 			// Layer 2: Tie-breaker logic if scores are equal.
-			for _, key := range tieBreakerKeys {
-				maxI, okI := extractMaxFromCombo(resI.Combination, key)
-				maxJ, okJ := extractMaxFromCombo(resJ.Combination, key)
-
-				// Only compare if both combinations have this tie-breaker key.
-				if okI && okJ {
-					if maxI != maxJ {
-						// The user wants the one with the HIGHER max value to be ranked higher (return true).
-						return maxI > maxJ
-					}
-				}
+			// The combination with the WIDER range is ranked higher.
+			if c := compareTieBreakers(resI.Combination, resJ.Combination); c != 0 {
+				return c > 0
 			}
 
 			// Layer 3: Final fallback for deterministic sorting if all else is equal.
@@ -512,7 +664,18 @@ func ProcessFinalResults(rawResults []Result) []Result {
 		// *** END OF MODIFIED SORTING LOGIC ***
 
 		// Keep only the top 10
-		if len(relevantResults) > 10 {
+		if overlap != nil {
+			var diversified []Result
+			for i, res := range relevantResults {
+				if len(diversified) == 10 || i == 10*overlapScanFactor {
+					break
+				}
+				if !overlap.overlaps(res, diversified) {
+					diversified = append(diversified, res)
+				}
+			}
+			relevantResults = diversified
+		} else if len(relevantResults) > 10 {
 			relevantResults = relevantResults[:10]
 		}
 		if len(relevantResults) > 0 {
@@ -569,16 +732,9 @@ func ProcessFinalResults(rawResults []Result) []Result {
 		}
 
 		// Layer 2: Tie-breaker logic if scores are equal.
-		for _, key := range tieBreakerKeys {
-			maxI, okI := extractMaxFromCombo(resI.Combination, key)
-			maxJ, okJ := extractMaxFromCombo(resJ.Combination, key)
-
-			if okI && okJ {
-				if maxI != maxJ {
-					// Rank the one with the HIGHER max value higher.
-					return maxI > maxJ
-				}
-			}
+		// Rank the one with the WIDER range higher.
+		if c := compareTieBreakers(resI.Combination, resJ.Combination); c != 0 {
+			return c > 0
 		}
 
 		// Layer 3: Final fallback for deterministic sorting.
@@ -589,34 +745,3 @@ func ProcessFinalResults(rawResults []Result) []Result {
 
 	return finalResults
 }
-
-func IsS2Setup(settings map[string]interface{}) bool {
-	// 1. Safely get the predefinedFilters slice from the settings map.
-	filtersInterface, ok := settings["predefinedFilters"].([]interface{})
-	if !ok {
-		// If the key doesn't exist or is not a slice, it can't be an S2 run.
-		return false
-	}
-
-	// 2. Iterate through the filters to find the specific "S2" setup filter.
-	for _, filterInterface := range filtersInterface {
-		// Assert that the filter is a map.
-		filter, ok := filterInterface.(map[string]interface{})
-		if !ok {
-			continue // Skip if the element isn't a map.
-		}
-
-		// Check for the exact conditions that define the S2 filter.
-		isSetupColumn := filter["columnHeader"] == "Setup"
-		isS2Condition := filter["condition"] == "S2"
-		isExactType := filter["type"] == "exact" // Good practice to check the type as well
-
-		if isSetupColumn && isS2Condition && isExactType {
-			// We found it. We can stop searching and return true immediately.
-			return true
-		}
-	}
-
-	// If we finish the loop without finding the filter, it doesn't exist.
-	return false
-}
@@ -0,0 +1,52 @@
+package optimizer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMetricsAccumulator(t *testing.T) {
+	type trade struct {
+		pnl float64
+		won bool
+	}
+	tests := []struct {
+		name         string
+		trades       []trade
+		winRate      float64
+		profitFactor float64
+		netProfit    float64
+		maxDrawdown  float64
+	}{
+		{"no trades", nil, 0, 0, 0, 0},
+		{"wins and losses", []trade{{200, true}, {-100, false}, {-100, false}, {300, true}}, 0.5, 2.5, 300, 200},
+		{"only wins", []trade{{100, true}}, 1, math.Inf(1), 100, 0},
+		// A win sized to nothing by a blown account still counts as a win.
+		{"win without size", []trade{{100, true}, {0, true}, {-50, false}}, 2.0 / 3, 2, 50, 50},
+		{"break-even is a loss", []trade{{100, true}, {0, false}}, 0.5, math.Inf(1), 100, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var acc metricsAccumulator
+			for _, trade := range tt.trades {
+				acc.add(trade.pnl, trade.won)
+			}
+			metrics := acc.metrics()
+			if metrics.TotalTradesThisStrategy != len(tt.trades) {
+				t.Errorf("trades = %d, want %d", metrics.TotalTradesThisStrategy, len(tt.trades))
+			}
+			if math.Abs(metrics.WinRate-tt.winRate) > 1e-9 {
+				t.Errorf("win rate = %g, want %g", metrics.WinRate, tt.winRate)
+			}
+			if metrics.ProfitFactor != tt.profitFactor && math.Abs(metrics.ProfitFactor-tt.profitFactor) > 1e-9 {
+				t.Errorf("profit factor = %g, want %g", metrics.ProfitFactor, tt.profitFactor)
+			}
+			if metrics.NetProfit != tt.netProfit {
+				t.Errorf("net profit = %g, want %g", metrics.NetProfit, tt.netProfit)
+			}
+			if metrics.MaxDrawdown != tt.maxDrawdown {
+				t.Errorf("max drawdown = %g, want %g", metrics.MaxDrawdown, tt.maxDrawdown)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package optimizer
+
+import (
+	"fmt"
+	"go-optimizer/utils"
+	"time"
+)
+
+// addCalendarColumns parses the Date column and adds the calendar columns derived from it:
+// Date_Key (yyyymmdd), Day_Of_Week (1 = Monday ... 7 = Sunday), Month and Week_Of_Month.
+func addCalendarColumns(table *TradeTable) error {
+	dateColumn := table.Column("Date")
+	if dateColumn == nil || dateColumn.Kind != StringColumn {
+		return fmt.Errorf("trade table has no Date column")
+	}
+
+	dateKeys := table.AddColumn("Date_Key", IntColumn)
+	dayOfWeek := table.AddColumn("Day_Of_Week", IntColumn)
+	month := table.AddColumn("Month", IntColumn)
+	weekOfMonth := table.AddColumn("Week_Of_Month", IntColumn)
+
+	invalidDates := 0
+	for row, value := range dateColumn.Strings {
+		date, err := utils.ParseTradeDate(value)
+		if err != nil {
+			invalidDates++
+			continue
+		}
+
+		dateKeys.Ints[row] = utils.DateKey(date)
+		dayOfWeek.Ints[row] = isoWeekday(date)
+		month.Ints[row] = int(date.Month())
+		weekOfMonth.Ints[row] = (date.Day()-1)/7 + 1
+	}
+
+	if invalidDates > 0 {
+		debugLog.Printf("WARNING: %d trades have an unparseable Date; their calendar columns are empty.", invalidDates)
+	}
+	return nil
+}
+
+// isoWeekday returns the ISO day of the week, 1 = Monday ... 7 = Sunday.
+func isoWeekday(date time.Time) int {
+	if date.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(date.Weekday())
+}
+
+func isWeekend(date time.Time) bool {
+	return date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+}
+
+// addTradingDayColumns adds the First/Last_Trading_Day_Of_Month flags. Trading days are
+// Monday to Friday without the full holidays, keyed by date key; half days are trading days.
+// It runs after the calendar and holiday columns, with no holidays if the exchange calendar
+// is unavailable.
+func addTradingDayColumns(table *TradeTable, holidays map[int]bool) {
+	firstTradingDay := table.AddColumn("First_Trading_Day_Of_Month", BoolColumn)
+	lastTradingDay := table.AddColumn("Last_Trading_Day_Of_Month", BoolColumn)
+
+	for _, trade := range table.Trades() {
+		key := trade.Int("Date_Key")
+		if key == 0 {
+			continue
+		}
+		date := utils.DateFromKey(key)
+		firstTradingDay.Bools[trade.Row] = date.Equal(firstTradingDayOfMonth(date, holidays))
+		lastTradingDay.Bools[trade.Row] = date.Equal(lastTradingDayOfMonth(date, holidays))
+	}
+}
+
+func isTradingDay(date time.Time, holidays map[int]bool) bool {
+	return !isWeekend(date) && !holidays[utils.DateKey(date)]
+}
+
+func firstTradingDayOfMonth(date time.Time, holidays map[int]bool) time.Time {
+	day := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for !isTradingDay(day, holidays) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+func lastTradingDayOfMonth(date time.Time, holidays map[int]bool) time.Time {
+	day := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+	for !isTradingDay(day, holidays) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// resolveDateRange turns a dateRange filter condition into an inclusive range of date keys.
+// The condition holds absolute "from"/"to" dates (either may be omitted) or "lastMonths",
+// which counts back from the most recent trade, so a run on an old export stays meaningful.
+// Trade data without a Date column cannot be restricted and is an error rather than dropped.
+func resolveDateRange(condition map[string]interface{}, trades []Trade) (int, int, error) {
+	fromKey, toKey := 0, 99991231
+	if len(trades) > 0 && trades[0].Column("Date_Key") == nil {
+		return 0, 0, fmt.Errorf("trade data has no Date column")
+	}
+
+	if lastMonths, ok := condition["lastMonths"].(float64); ok {
+		latestKey := 0
+		for _, trade := range trades {
+			if key := trade.Int("Date_Key"); key > latestKey {
+				latestKey = key
+			}
+		}
+		if latestKey == 0 {
+			return 0, 0, fmt.Errorf("no trade has a valid Date")
+		}
+		fromKey = utils.DateKey(utils.DateFromKey(latestKey).AddDate(0, -int(lastMonths), 0))
+		return fromKey, toKey, nil
+	}
+
+	if from, ok := condition["from"].(string); ok && from != "" {
+		date, err := utils.ParseTradeDate(from)
+		if err != nil {
+			return 0, 0, err
+		}
+		fromKey = utils.DateKey(date)
+	}
+	if to, ok := condition["to"].(string); ok && to != "" {
+		date, err := utils.ParseTradeDate(to)
+		if err != nil {
+			return 0, 0, err
+		}
+		toKey = utils.DateKey(date)
+	}
+	return fromKey, toKey, nil
+}
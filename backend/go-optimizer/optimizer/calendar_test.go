@@ -0,0 +1,49 @@
+package optimizer
+
+import "testing"
+
+func TestTradingDayColumnsSkipHolidays(t *testing.T) {
+	tests := []struct {
+		instrument string
+		date       string
+		first      bool
+		last       bool
+	}{
+		// New Year's Day is a XETRA holiday, so the month starts on 2 January.
+		{"DAX", "2024-01-01", false, false},
+		{"DAX", "2024-01-02", true, false},
+		// 24 to 26 and 31 December are XETRA holidays.
+		{"DAX", "2024-12-30", false, true},
+		{"DAX", "2024-12-31", false, false},
+		// Without an exchange calendar only weekends are skipped.
+		{"EURUSD", "2024-01-01", true, false},
+		{"EURUSD", "2024-12-31", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.instrument+" "+tt.date, func(t *testing.T) {
+			table := NewTradeTable()
+			table.AddColumn("Date", StringColumn)
+			if err := table.AppendRow([]interface{}{tt.date}); err != nil {
+				t.Fatal(err)
+			}
+			AddDerivedColumns(table, map[string]interface{}{}, DerivedInputs{Instrument: tt.instrument})
+			trade := table.Trades()[0]
+			if got := trade.Bool("First_Trading_Day_Of_Month"); got != tt.first {
+				t.Errorf("First_Trading_Day_Of_Month = %v, want %v", got, tt.first)
+			}
+			if got := trade.Bool("Last_Trading_Day_Of_Month"); got != tt.last {
+				t.Errorf("Last_Trading_Day_Of_Month = %v, want %v", got, tt.last)
+			}
+		})
+	}
+}
+
+func TestDateRangeWithoutDateColumn(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"Entered", BoolColumn}}, []interface{}{true})
+	filters := []interface{}{
+		map[string]interface{}{"type": "dateRange", "condition": map[string]interface{}{"from": "2024-01-01"}},
+	}
+	if _, err := ApplyPredefinedFilters(trades, filters); err == nil {
+		t.Error("dateRange filter on trades without a Date column returned no error")
+	}
+}
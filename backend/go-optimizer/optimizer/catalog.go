@@ -0,0 +1,309 @@
+package optimizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// virtualColumns are criteria keys that are evaluated by ApplyFilters or CalculateMetrics
+// without reading a trade column of the same name.
+var virtualColumns = map[string]bool{
+	"CandleSizeTPRatio": true,
+	sessionCriterion:    true,
+}
+
+var validNumericModes = map[string]bool{
+	"":                 true,
+	"PERMUTATION":      true,
+	"PERMUTATION_OPEN": true,
+	"MAX":              true,
+	"MIN":              true,
+	"OUTSIDE":          true,
+}
+
+// Strategies is the active strategy catalog resolved by SetCatalogs, in catalog order.
+var Strategies []Strategy
+
+// Strategy is a validated entry of the strategy catalog.
+type Strategy struct {
+	Name         string
+	SLPipsColumn string
+	// LTA strategies are only scored for combinations with Closed_In_LTA.
+	LTA            bool
+	Setups         []string
+	ExcludedSetups []string
+	// Legs holds a single leg for a simple strategy.
+	Legs []strategyLeg
+	// BreakEvenLeg is -1 without a break-even rule.
+	BreakEvenLeg        int
+	BreakEvenOffsetPips float64
+}
+
+// SetCatalogs validates the given catalogs and makes them the active
+// SelectableCombinations, TradeStrategies and TradingSessions, with the strategies
+// resolved into Strategies.
+func SetCatalogs(combinations, strategies, sessions []map[string]interface{}) error {
+	validCombinations, err := ValidateSelectableCombinations(combinations)
+	if err != nil {
+		return err
+	}
+	resolvedStrategies, err := ValidateTradeStrategies(strategies)
+	if err != nil {
+		return err
+	}
+	validSessions, err := ValidateTradingSessions(sessions)
+	if err != nil {
+		return err
+	}
+	SelectableCombinations = validCombinations
+	TradeStrategies = strategies
+	Strategies = resolvedStrategies
+	TradingSessions = validSessions
+	return nil
+}
+
+// findStrategy returns the active strategy of the given name.
+func findStrategy(name string) (Strategy, bool) {
+	for _, strategy := range Strategies {
+		if strategy.Name == name {
+			return strategy, true
+		}
+	}
+	return Strategy{}, false
+}
+
+// ValidateSelectableCombinations checks the structure of a criteria catalog and fills in
+// optional keys, so BuildEnabledCriteria can rely on them. Columns are checked per run by
+// ValidateEnabledCriteria, once the trade table has been loaded.
+func ValidateSelectableCombinations(combinations []map[string]interface{}) ([]map[string]interface{}, error) {
+	for i, def := range combinations {
+		name, ok := def["name"].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("criteria group %d has no name", i)
+		}
+		criteriaMaps, ok := def["criterias"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("criteria group %q has no 'criterias' list", name)
+		}
+
+		for j, critMapIntf := range criteriaMaps {
+			critMap, ok := critMapIntf.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("criterion %d of group %q is not an object", j, name)
+			}
+
+			columnHeader, _ := critMap["columnHeader"].(string)
+			if columnHeader == "" {
+				return nil, fmt.Errorf("criterion %d of group %q has no columnHeader", j, name)
+			}
+
+			critType, _ := critMap["type"].(string)
+			if critType != "exact" && critType != "numericRange" {
+				return nil, fmt.Errorf("criterion %s of group %q has unknown type %q", columnHeader, name, critType)
+			}
+
+			if _, ok := critMap["testValues"].([]interface{}); !ok {
+				critMap["testValues"] = []interface{}{}
+			}
+			if _, ok := critMap["thresholds"].([]interface{}); !ok {
+				critMap["thresholds"] = []interface{}{}
+			}
+			mode, _ := critMap["mode"].(string)
+			if critType == "numericRange" && !validNumericModes[mode] {
+				return nil, fmt.Errorf("criterion %s of group %q has unknown mode %q", columnHeader, name, mode)
+			}
+			critMap["mode"] = mode
+		}
+	}
+	return combinations, nil
+}
+
+// ValidateTradeStrategies checks the structure of a strategy catalog and resolves its
+// entries. The referenced columns are checked against the loaded trades by
+// ValidateStrategyColumns.
+func ValidateTradeStrategies(strategies []map[string]interface{}) ([]Strategy, error) {
+	if len(strategies) == 0 {
+		return nil, fmt.Errorf("strategy catalog is empty")
+	}
+
+	var resolved []Strategy
+	seenNames := make(map[string]struct{})
+	for i, definition := range strategies {
+		name, ok := definition["name"].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("strategy %d has no name", i)
+		}
+		if _, seen := seenNames[name]; seen {
+			return nil, fmt.Errorf("strategy %q is defined more than once", name)
+		}
+		seenNames[name] = struct{}{}
+
+		strategy := Strategy{Name: name}
+		strategy.SLPipsColumn, _ = definition["slPipsColumn"].(string)
+		if strategy.SLPipsColumn == "" {
+			return nil, fmt.Errorf("strategy %q has no slPipsColumn", name)
+		}
+		var err error
+		strategy.Legs, err = strategyLegs(definition)
+		if err != nil {
+			return nil, fmt.Errorf("strategy %q %w", name, err)
+		}
+		strategy.BreakEvenLeg, strategy.BreakEvenOffsetPips, err = breakEvenRule(definition, strategy.Legs)
+		if err != nil {
+			return nil, fmt.Errorf("strategy %q: %w", name, err)
+		}
+		strategy.LTA, _ = definition["lta"].(bool)
+
+		strategy.Setups, err = stringList(definition["setups"])
+		if err != nil {
+			return nil, fmt.Errorf("strategy %q has invalid setups: %w", name, err)
+		}
+		strategy.ExcludedSetups, err = stringList(definition["excludedSetups"])
+		if err != nil {
+			return nil, fmt.Errorf("strategy %q has invalid excludedSetups: %w", name, err)
+		}
+		// Catalogs from before setup applicability mark S2-only strategies with "s2";
+		// every other strategy ignored S2 trades.
+		if s2, ok := definition["s2"].(bool); ok && !s2 {
+			_, hasSetups := definition["setups"]
+			_, hasExcluded := definition["excludedSetups"]
+			if !hasSetups && !hasExcluded {
+				strategy.ExcludedSetups = []string{"S2"}
+			}
+		}
+		resolved = append(resolved, strategy)
+	}
+	return resolved, nil
+}
+
+// ValidateStrategyColumns checks that every column the active strategies read exists in
+// the trade table with a usable type, since CalculateMetrics reads them for every trade.
+func ValidateStrategyColumns(table *TradeTable) error {
+	for _, strategy := range Strategies {
+		name := strategy.Name
+		columns := map[string]ColumnKind{strategy.SLPipsColumn: FloatColumn}
+		for _, leg := range strategy.Legs {
+			columns[leg.winColumn] = BoolColumn
+			columns[leg.tpPipsColumn] = FloatColumn
+			if leg.rangeBreakoutColumn != "" {
+				columns[leg.rangeBreakoutColumn] = BoolColumn
+			}
+		}
+
+		for column, kind := range columns {
+			col := table.Column(column)
+			if col == nil {
+				return fmt.Errorf("strategy %q: column %q does not exist in the trade table", name, column)
+			}
+			if kind == BoolColumn && col.Kind != BoolColumn {
+				return fmt.Errorf("strategy %q: column %q is not a boolean column", name, column)
+			}
+			if kind == FloatColumn && col.Kind != FloatColumn && col.Kind != IntColumn {
+				return fmt.Errorf("strategy %q: column %q is not a numeric column", name, column)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateExclusiveCombinations rejects "combinationsToTest" settings that enable two groups
+// testing the same column, such as "Entry Delay" and "Entry Delay Range": their filters on
+// the column would be combined as if they were independent criteria.
+func ValidateExclusiveCombinations(settings map[string]interface{}) error {
+	enabledComboNames := make(map[string]bool)
+	enabledComboNamesInterface, _ := settings["combinationsToTest"].([]interface{})
+	for _, name := range enabledComboNamesInterface {
+		if n, ok := name.(string); ok {
+			enabledComboNames[n] = true
+		}
+	}
+
+	groupByColumn := make(map[string]string)
+	for _, def := range SelectableCombinations {
+		defName, _ := def["name"].(string)
+		if !enabledComboNames[defName] {
+			continue
+		}
+		criteriaMaps, _ := def["criterias"].([]interface{})
+		for _, critMapIntf := range criteriaMaps {
+			critMap, _ := critMapIntf.(map[string]interface{})
+			column, _ := critMap["columnHeader"].(string)
+			if other, ok := groupByColumn[column]; ok && other != defName {
+				return fmt.Errorf("groups %q and %q both test %s; enable only one of them", other, defName, column)
+			}
+			groupByColumn[column] = defName
+		}
+	}
+	return nil
+}
+
+// ValidateEnabledCriteria removes criteria whose columns the trade table does not carry, so
+// they neither multiply the search space nor show up in the results as if they mattered.
+// It returns the remaining criteria and one warning per removed criterion.
+func ValidateEnabledCriteria(criteria []CombinationCriterion, table *TradeTable) ([]CombinationCriterion, []string) {
+	var validCriteria []CombinationCriterion
+	var warnings []string
+
+	for _, criterion := range criteria {
+		var missing []string
+		for _, part := range criterionColumnNames(criterion.ColumnHeader) {
+			if !virtualColumns[part] && !table.HasColumn(part) {
+				missing = append(missing, part)
+			}
+		}
+		if criterion.ColumnHeader == sessionCriterion {
+			var missingSessions []string
+			criterion, missingSessions = availableSessions(criterion, table)
+			if len(missingSessions) > 0 {
+				warnings = append(warnings, fmt.Sprintf("Session criterion skips unavailable column(s) %s.", strings.Join(missingSessions, ", ")))
+			}
+			if len(criterion.TestValues) <= 1 {
+				missing = append(missing, missingSessions...)
+			}
+		}
+		if len(missing) > 0 {
+			warnings = append(warnings, fmt.Sprintf("Criterion %s was skipped: column(s) %s not available in the trade data.", criterion.ColumnHeader, strings.Join(missing, ", ")))
+			continue
+		}
+		validCriteria = append(validCriteria, criterion)
+	}
+	return validCriteria, warnings
+}
+
+// availableSessions drops the session values whose Session_<name> column was not derived,
+// because the session is not in the catalog or the trade times could not be converted.
+func availableSessions(criterion CombinationCriterion, table *TradeTable) (CombinationCriterion, []string) {
+	var values []interface{}
+	var missing []string
+	for _, value := range criterion.TestValues {
+		name, ok := value.(string)
+		if ok && !table.HasColumn(sessionColumnPrefix+name) {
+			missing = append(missing, sessionColumnPrefix+name)
+			continue
+		}
+		values = append(values, value)
+	}
+	criterion.TestValues = values
+	return criterion, missing
+}
+
+// stringList converts a JSON list of strings; a missing list is empty.
+func stringList(value interface{}) ([]string, error) {
+	switch list := value.(type) {
+	case nil:
+		return []string{}, nil
+	case []string:
+		return list, nil
+	case []interface{}:
+		strs := make([]string, 0, len(list))
+		for _, item := range list {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%v is not a string", item)
+			}
+			strs = append(strs, str)
+		}
+		return strs, nil
+	}
+	return nil, fmt.Errorf("not a list")
+}
@@ -0,0 +1,95 @@
+package optimizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateTradeStrategies(t *testing.T) {
+	simple := func(extra map[string]interface{}) map[string]interface{} {
+		strategy := map[string]interface{}{"name": "1RR PW", "winColumn": "W", "tpPipsColumn": "TP", "slPipsColumn": "SL"}
+		for key, value := range extra {
+			strategy[key] = value
+		}
+		return strategy
+	}
+	legs := []interface{}{
+		map[string]interface{}{"fraction": 0.5, "winColumn": "W1", "tpPipsColumn": "TP1"},
+		map[string]interface{}{"fraction": 0.5, "winColumn": "W2", "tpPipsColumn": "TP2"},
+	}
+
+	tests := []struct {
+		name       string
+		strategies []map[string]interface{}
+		want       Strategy
+		wantErr    bool
+	}{
+		{
+			name:       "simple strategy",
+			strategies: []map[string]interface{}{simple(map[string]interface{}{"lta": true, "setups": []interface{}{"S1"}})},
+			want: Strategy{Name: "1RR PW", SLPipsColumn: "SL", LTA: true, Setups: []string{"S1"}, ExcludedSetups: []string{},
+				Legs: []strategyLeg{{fraction: 1, winColumn: "W", tpPipsColumn: "TP"}}, BreakEvenLeg: -1},
+		},
+		{
+			name:       "legacy s2 flag excludes S2",
+			strategies: []map[string]interface{}{simple(map[string]interface{}{"s2": false})},
+			want: Strategy{Name: "1RR PW", SLPipsColumn: "SL", Setups: []string{}, ExcludedSetups: []string{"S2"},
+				Legs: []strategyLeg{{fraction: 1, winColumn: "W", tpPipsColumn: "TP"}}, BreakEvenLeg: -1},
+		},
+		{
+			name:       "composite strategy from JSON",
+			strategies: []map[string]interface{}{{"name": "BE", "slPipsColumn": "SL", "legs": legs, "breakEvenLeg": 0.0, "breakEvenOffsetPips": 1.0}},
+			want: Strategy{Name: "BE", SLPipsColumn: "SL", Setups: []string{}, ExcludedSetups: []string{},
+				Legs:         []strategyLeg{{fraction: 0.5, winColumn: "W1", tpPipsColumn: "TP1"}, {fraction: 0.5, winColumn: "W2", tpPipsColumn: "TP2"}},
+				BreakEvenLeg: 0, BreakEvenOffsetPips: 1},
+		},
+		{name: "empty catalog", wantErr: true},
+		{name: "duplicate name", strategies: []map[string]interface{}{simple(nil), simple(nil)}, wantErr: true},
+		{name: "no SL column", strategies: []map[string]interface{}{{"name": "X", "winColumn": "W", "tpPipsColumn": "TP"}}, wantErr: true},
+		{name: "break-even on the last leg", strategies: []map[string]interface{}{{"name": "BE", "slPipsColumn": "SL", "legs": legs, "breakEvenLeg": 1.0}}, wantErr: true},
+		{name: "invalid setups", strategies: []map[string]interface{}{simple(map[string]interface{}{"setups": "S1"})}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategies, err := ValidateTradeStrategies(tt.strategies)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(strategies) != 1 || !reflect.DeepEqual(strategies[0], tt.want) {
+				t.Errorf("got %+v, want %+v", strategies, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltInCatalogsAreValid(t *testing.T) {
+	if err := SetCatalogs(SelectableCombinations, TradeStrategies, TradingSessions); err != nil {
+		t.Fatal(err)
+	}
+	if len(Strategies) != len(TradeStrategies) {
+		t.Errorf("resolved %d strategies from %d catalog entries", len(Strategies), len(TradeStrategies))
+	}
+}
+
+func TestValidateExclusiveCombinations(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  []interface{}
+		wantErr bool
+	}{
+		{"one entry delay group", []interface{}{"Entry Delay", "Holidays"}, false},
+		{"both entry delay groups", []interface{}{"Entry Delay", "Entry Delay Range"}, true},
+		{"nothing enabled", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExclusiveCombinations(map[string]interface{}{"combinationsToTest": tt.groups})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,5 +1,14 @@
 package optimizer
 
+// CatalogSeedVersion is the version of the built-in catalogs below. Bump it whenever a
+// built-in entry is added or changed: stored catalogs of an older version get the missing
+// built-in entries at startup, while stored entries of the same name are kept and reported
+// in the run warnings if they differ.
+const CatalogSeedVersion = 2
+
+// SelectableCombinations, TradeStrategies and TradingSessions are the built-in catalogs. At startup they are
+// replaced by the documents in the optimizer_catalog table, which are seeded from these
+// literals when missing and upgraded when stored by an older CatalogSeedVersion.
 var SelectableCombinations = []map[string]interface{}{
 	{
 		"name": "Gaussian",
@@ -78,22 +87,116 @@ var SelectableCombinations = []map[string]interface{}{
 			map[string]interface{}{"columnHeader": "S2_Previous_Support_Distance|S2_Previous_Resistance_Distance", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{10, 12.5, 15, 20, 25, 30, 35, 45, nil}, "mode": "MAX"},
 		},
 	},
+	// The *_Consecutive_Candles columns are signed: positive for a run of bullish candles,
+	// negative for a bearish run. "X|-X" reads the run in the trade's own direction,
+	// "-X|X" the run against it. Five choices per timeframe keep the group at 5^5
+	// combinations; wider ranges can be added per criterion in the stored catalog.
+	{
+		"name": "Consecutive Candles",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "M5_Consecutive_Candles|-M5_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{1, 2, 3, 5, nil}, "mode": "MIN"},
+			map[string]interface{}{"columnHeader": "M10_Consecutive_Candles|-M10_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{1, 2, 3, 5, nil}, "mode": "MIN"},
+			map[string]interface{}{"columnHeader": "M15_Consecutive_Candles|-M15_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{1, 2, 3, 5, nil}, "mode": "MIN"},
+			map[string]interface{}{"columnHeader": "M30_Consecutive_Candles|-M30_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{1, 2, 3, 5, nil}, "mode": "MIN"},
+			map[string]interface{}{"columnHeader": "H1_Consecutive_Candles|-H1_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{1, 2, 3, 5, nil}, "mode": "MIN"},
+		},
+	},
+	{
+		"name": "Consecutive Candles Against Max",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "-M5_Consecutive_Candles|M5_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{0, 1, 2, 3, nil}, "mode": "MAX"},
+			map[string]interface{}{"columnHeader": "-M10_Consecutive_Candles|M10_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{0, 1, 2, 3, nil}, "mode": "MAX"},
+			map[string]interface{}{"columnHeader": "-M15_Consecutive_Candles|M15_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{0, 1, 2, 3, nil}, "mode": "MAX"},
+			map[string]interface{}{"columnHeader": "-M30_Consecutive_Candles|M30_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{0, 1, 2, 3, nil}, "mode": "MAX"},
+			map[string]interface{}{"columnHeader": "-H1_Consecutive_Candles|H1_Consecutive_Candles", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{0, 1, 2, 3, nil}, "mode": "MAX"},
+		},
+	},
 	{
 		"name": "S3 Reversal Candle Size Min Max",
 		"criterias": []interface{}{
 			map[string]interface{}{"columnHeader": "S3_Reversal_Candle_Size", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{2, 5, 8, 10, 15, 18, 25, 30, nil}, "mode": "PERMUTATION"},
 		},
 	},
+	{
+		"name": "Day Of Week",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Day_Of_Week", "type": "exact", "testValues": []interface{}{1, 2, 3, 4, 5, nil}, "thresholds": []interface{}{}, "mode": ""},
+		},
+	},
+	{
+		"name": "Month",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Month", "type": "exact", "testValues": []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, nil}, "thresholds": []interface{}{}, "mode": ""},
+		},
+	},
+	{
+		"name": "Week Of Month",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Week_Of_Month", "type": "exact", "testValues": []interface{}{1, 2, 3, 4, 5, nil}, "thresholds": []interface{}{}, "mode": ""},
+		},
+	},
+	{
+		"name": "First Last Trading Day Of Month",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "First_Trading_Day_Of_Month", "type": "exact", "testValues": []interface{}{true, false, nil}, "thresholds": []interface{}{}, "mode": ""},
+			map[string]interface{}{"columnHeader": "Last_Trading_Day_Of_Month", "type": "exact", "testValues": []interface{}{true, false, nil}, "thresholds": []interface{}{}, "mode": ""},
+		},
+	},
+	{
+		"name": "Direction",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Direction", "type": "exact", "testValues": []interface{}{"BUY", "SELL", nil}, "thresholds": []interface{}{}, "mode": ""},
+		},
+	},
+	{
+		"name": "Entry Delay",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Entered_After_Seconds", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{30, 60, 120, 300, 600, 1800, nil}, "mode": "MAX"},
+		},
+	},
+	{
+		"name": "Entry Delay Range",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Entered_After_Seconds", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{0, 30, 60, 120, 300, 600, 1800, nil}, "mode": "PERMUTATION"},
+		},
+	},
+	{
+		"name": "Holidays",
+		// false excludes the days, nil keeps them.
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Is_Holiday_Period", "type": "exact", "testValues": []interface{}{false, nil}, "thresholds": []interface{}{}, "mode": ""},
+			map[string]interface{}{"columnHeader": "Is_Holiday_Adjacent", "type": "exact", "testValues": []interface{}{false, nil}, "thresholds": []interface{}{}, "mode": ""},
+		},
+	},
 	{
 		"name": "Market Open",
 		"criterias": []interface{}{
 			map[string]interface{}{"columnHeader": "Is_Before_Opening", "type": "exact", "testValues": []interface{}{true, nil}, "thresholds": []interface{}{}, "mode": ""},
 		},
 	},
+	{
+		"name": "Session",
+		// Empty testValues test every session of the TradingSessions catalog.
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Session", "type": "exact", "testValues": []interface{}{}, "thresholds": []interface{}{}, "mode": ""},
+		},
+	},
 	{
 		"name": "News",
 		"criterias": []interface{}{
-			map[string]interface{}{"columnHeader": "Upcoming_News", "type": "exact", "testValues": []interface{}{true, nil}, "thresholds": []interface{}{}, "mode": ""},
+			map[string]interface{}{"columnHeader": "Minutes_To_Nearest_News", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{15, 30, 60, 120, 240, nil}, "mode": "MIN"},
+		},
+	},
+	{
+		"name": "News Ahead",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Minutes_To_Next_News", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{15, 30, 60, 120, 240, nil}, "mode": "MIN"},
+		},
+	},
+	{
+		"name": "News Behind",
+		"criterias": []interface{}{
+			map[string]interface{}{"columnHeader": "Minutes_Since_Last_News", "type": "numericRange", "testValues": []interface{}{}, "thresholds": []interface{}{15, 30, 60, 120, 240, nil}, "mode": "MIN"},
 		},
 	},
 	{
@@ -104,17 +207,40 @@ var SelectableCombinations = []map[string]interface{}{
 	},
 }
 
+// A strategy applies to the trades of its "setups" (every setup if omitted), except those of
+// its "excludedSetups". LTA strategies are only scored for combinations with Closed_In_LTA.
 var TradeStrategies = []map[string]interface{}{
-	{"name": "1RR PW", "winColumn": "TP_1RR_PW_WIN", "tpPipsColumn": "TP_1RR_PW_PIPS", "slPipsColumn": "SL_PW_PIPS", "lta": false, "s2": false, "rangeBreakoutColumn": ""},
-	{"name": "1RR STR", "winColumn": "TP_1RR_STR_WIN", "tpPipsColumn": "TP_1RR_STR_PIPS", "slPipsColumn": "SL_STR_PIPS", "lta": false, "s2": false, "rangeBreakoutColumn": ""},
-	{"name": "SR LTA SL PW", "winColumn": "TP_SR_LTA_SL_PW_WIN", "tpPipsColumn": "TP_SR_LTA_PIPS", "slPipsColumn": "SL_PW_PIPS", "rangeBreakoutColumn": "LTA_Range_Breakout", "lta": true, "s2": false},
-	{"name": "SR LTA SL STR", "winColumn": "TP_SR_LTA_SL_STR_WIN", "tpPipsColumn": "TP_SR_LTA_PIPS", "slPipsColumn": "SL_STR_PIPS", "rangeBreakoutColumn": "LTA_Range_Breakout", "lta": true, "s2": false},
-	{"name": "SR NEAR SL PW", "winColumn": "TP_SR_NEAREST_SL_PW_WIN", "tpPipsColumn": "TP_SR_NEAREST_PIPS", "slPipsColumn": "SL_PW_PIPS", "rangeBreakoutColumn": "Nearest_Range_Breakout", "lta": false, "s2": false},
-	{"name": "SR NEAR SL STR", "winColumn": "TP_SR_NEAREST_SL_STR_WIN", "tpPipsColumn": "TP_SR_NEAREST_PIPS", "slPipsColumn": "SL_STR_PIPS", "rangeBreakoutColumn": "Nearest_Range_Breakout", "lta": false, "s2": false},
-	{"name": "SR STATIC SL PW", "winColumn": "TP_SR_STATIC_SL_PW_WIN", "tpPipsColumn": "TP_SR_STATIC_PIPS", "slPipsColumn": "SL_PW_PIPS", "rangeBreakoutColumn": "Static_Range_Breakout", "lta": false, "s2": false},
-	{"name": "SR STATIC SL STR", "winColumn": "TP_SR_STATIC_SL_STR_WIN", "tpPipsColumn": "TP_SR_STATIC_PIPS", "slPipsColumn": "SL_STR_PIPS", "rangeBreakoutColumn": "Static_Range_Breakout", "lta": false, "s2": false},
-	{"name": "SR CURR SL PW", "winColumn": "TP_SR_CURRENT_PW_WIN", "tpPipsColumn": "TP_SR_CURRENT_PIPS", "slPipsColumn": "SL_PW_PIPS", "rangeBreakoutColumn": "Current_Range_Breakout", "lta": false, "s2": true},
-	{"name": "SR CURR SL STR", "winColumn": "TP_SR_CURRENT_STR_WIN", "tpPipsColumn": "TP_SR_CURRENT_PIPS", "slPipsColumn": "SL_STR_PIPS", "rangeBreakoutColumn": "Current_Range_Breakout", "lta": false, "s2": true},
+	{"name": "1RR PW", "winColumn": "TP_1RR_PW_WIN", "tpPipsColumn": "TP_1RR_PW_PIPS", "slPipsColumn": "SL_PW_PIPS", "lta": false, "rangeBreakoutColumn": "", "excludedSetups": []interface{}{"S2"}},
+	{"name": "1RR STR", "winColumn": "TP_1RR_STR_WIN", "tpPipsColumn": "TP_1RR_STR_PIPS", "slPipsColumn": "SL_STR_PIPS", "lta": false, "rangeBreakoutColumn": "", "excludedSetups": []interface{}{"S2"}},
+	{"name": "SR LTA SL PW", "winColumn": "TP_SR_LTA_SL_PW_WIN", "tpPipsColumn": "TP_SR_LTA_PIPS", "slPipsColumn": "SL_PW_PIPS", "rangeBreakoutColumn": "LTA_Range_Breakout", "lta": true, "excludedSetups": []interface{}{"S2"}},
+	{"name": "SR LTA SL STR", "winColumn": "TP_SR_LTA_SL_STR_WIN", "tpPipsColumn": "TP_SR_LTA_PIPS", "slPipsColumn": "SL_STR_PIPS", "rangeBreakoutColumn": "LTA_Range_Breakout", "lta": true, "excludedSetups": []interface{}{"S2"}},
+	{"name": "SR NEAR SL PW", "winColumn": "TP_SR_NEAREST_SL_PW_WIN", "tpPipsColumn": "TP_SR_NEAREST_PIPS", "slPipsColumn": "SL_PW_PIPS", "rangeBreakoutColumn": "Nearest_Range_Breakout", "lta": false, "excludedSetups": []interface{}{"S2"}},
+	{"name": "SR NEAR SL STR", "winColumn": "TP_SR_NEAREST_SL_STR_WIN", "tpPipsColumn": "TP_SR_NEAREST_PIPS", "slPipsColumn": "SL_STR_PIPS", "rangeBreakoutColumn": "Nearest_Range_Breakout", "lta": false, "excludedSetups": []interface{}{"S2"}},
+	{"name": "SR STATIC SL PW", "winColumn": "TP_SR_STATIC_SL_PW_WIN", "tpPipsColumn": "TP_SR_STATIC_PIPS", "slPipsColumn": "SL_PW_PIPS", "rangeBreakoutColumn": "Static_Range_Breakout", "lta": false, "excludedSetups": []interface{}{"S2"}},
+	{"name": "SR STATIC SL STR", "winColumn": "TP_SR_STATIC_SL_STR_WIN", "tpPipsColumn": "TP_SR_STATIC_PIPS", "slPipsColumn": "SL_STR_PIPS", "rangeBreakoutColumn": "Static_Range_Breakout", "lta": false, "excludedSetups": []interface{}{"S2"}},
+	{"name": "SR CURR SL PW", "winColumn": "TP_SR_CURRENT_PW_WIN", "tpPipsColumn": "TP_SR_CURRENT_PIPS", "slPipsColumn": "SL_PW_PIPS", "rangeBreakoutColumn": "Current_Range_Breakout", "lta": false},
+	{"name": "SR CURR SL STR", "winColumn": "TP_SR_CURRENT_STR_WIN", "tpPipsColumn": "TP_SR_CURRENT_PIPS", "slPipsColumn": "SL_STR_PIPS", "rangeBreakoutColumn": "Current_Range_Breakout", "lta": false},
+	// Composite strategies close a fraction of the position at each leg's target; after the
+	// "breakEvenLeg" target is hit, the stop of the rest moves to break-even.
+	{"name": "1RR + SR NEAR BE PW", "slPipsColumn": "SL_PW_PIPS", "lta": false, "excludedSetups": []interface{}{"S2"}, "breakEvenLeg": 0, "legs": []interface{}{
+		map[string]interface{}{"fraction": 0.5, "winColumn": "TP_1RR_PW_WIN", "tpPipsColumn": "TP_1RR_PW_PIPS"},
+		map[string]interface{}{"fraction": 0.5, "winColumn": "TP_SR_NEAREST_SL_PW_WIN", "tpPipsColumn": "TP_SR_NEAREST_PIPS", "rangeBreakoutColumn": "Nearest_Range_Breakout"},
+	}},
+	{"name": "1RR + SR NEAR BE STR", "slPipsColumn": "SL_STR_PIPS", "lta": false, "excludedSetups": []interface{}{"S2"}, "breakEvenLeg": 0, "legs": []interface{}{
+		map[string]interface{}{"fraction": 0.5, "winColumn": "TP_1RR_STR_WIN", "tpPipsColumn": "TP_1RR_STR_PIPS"},
+		map[string]interface{}{"fraction": 0.5, "winColumn": "TP_SR_NEAREST_SL_STR_WIN", "tpPipsColumn": "TP_SR_NEAREST_PIPS", "rangeBreakoutColumn": "Nearest_Range_Breakout"},
+	}},
+}
+
+// TradingSessions are the named windows of the "Session" criterion, in the local time of
+// their zone. A session with "dstAware": false keeps its window in standard (winter) time.
+var TradingSessions = []map[string]interface{}{
+	{"name": "Xetra_PreOpen", "zone": "Frankfurt", "start": "08:00", "end": "09:00", "dstAware": true},
+	{"name": "Xetra_Open", "zone": "Frankfurt", "start": "09:00", "end": "10:00", "dstAware": true},
+	{"name": "London_Open", "zone": "London", "start": "08:00", "end": "09:00", "dstAware": true},
+	{"name": "London_NY_Overlap", "zone": "NewYork", "start": "08:00", "end": "11:30", "dstAware": true},
+	{"name": "US_PreOpen", "zone": "NewYork", "start": "08:00", "end": "09:30", "dstAware": true},
+	{"name": "US_Cash_Open", "zone": "NewYork", "start": "09:30", "end": "10:30", "dstAware": true},
 }
 
 func BuildEnabledCriteria(settings map[string]interface{}) []CombinationCriterion {
@@ -138,12 +264,17 @@ func BuildEnabledCriteria(settings map[string]interface{}) []CombinationCriterio
 			criteriaMaps := def["criterias"].([]interface{})
 			for _, critMapIntf := range criteriaMaps {
 				critMap := critMapIntf.(map[string]interface{})
+				testValues := critMap["testValues"].([]interface{})
+				if critMap["columnHeader"] == sessionCriterion && len(testValues) == 0 {
+					testValues = sessionTestValues()
+				}
 				enabledCombinationDefs = append(enabledCombinationDefs, CombinationCriterion{
-					ColumnHeader: critMap["columnHeader"].(string),
-					Type:         critMap["type"].(string),
-					TestValues:   critMap["testValues"].([]interface{}),
-					Thresholds:   critMap["thresholds"].([]interface{}),
-					Mode:         critMap["mode"].(string),
+					ColumnHeader:    critMap["columnHeader"].(string),
+					Type:            critMap["type"].(string),
+					TestValues:      testValues,
+					Thresholds:      critMap["thresholds"].([]interface{}),
+					Mode:            critMap["mode"].(string),
+					ThresholdSource: critMap["thresholdSource"],
 				})
 			}
 		}
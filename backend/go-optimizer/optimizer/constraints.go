@@ -0,0 +1,203 @@
+package optimizer
+
+import (
+	"fmt"
+	"go-optimizer/utils"
+	"sort"
+	"sync"
+)
+
+// Rejection reasons of the strategy constraints, in the order they are checked.
+const (
+	rejectMinTrades        = "minTrades"
+	rejectMinTradesPerYear = "minTradesPerYear"
+	rejectMinWinRate       = "minWinRate"
+	rejectMinProfitFactor  = "minProfitFactor"
+	rejectMaxDrawdown      = "maxDrawdown"
+)
+
+// constraintKeys are the keys of a constraint set in the "strategyConstraints" setting.
+var constraintKeys = []string{rejectMinTrades, rejectMinTradesPerYear, rejectMinWinRate, rejectMinProfitFactor, rejectMaxDrawdown}
+
+// StrategyConstraints are the hard limits a strategy's metrics must meet on a dataset to be
+// scored. They come from the "strategyConstraints" setting: its minTrades, minTradesPerYear,
+// minWinRate (percent), minProfitFactor and maxDrawdown apply to every strategy, and the
+// same keys under "perStrategy", keyed by strategy name, override them for one strategy.
+// A limit of 0 is not checked.
+type StrategyConstraints struct {
+	limits map[string]map[string]float64
+	// years is the time span of the dataset, the base of minTradesPerYear.
+	years float64
+}
+
+// NewStrategyConstraints returns the constraints of a dataset's trades.
+func NewStrategyConstraints(settings map[string]interface{}, trades []Trade) StrategyConstraints {
+	setting, _ := settings["strategyConstraints"].(map[string]interface{})
+	if len(setting) == 0 {
+		return StrategyConstraints{}
+	}
+	global := constraintLimits(setting, nil)
+	perStrategy, _ := setting["perStrategy"].(map[string]interface{})
+
+	constraints := StrategyConstraints{limits: make(map[string]map[string]float64), years: datasetYears(trades)}
+	for _, strategy := range Strategies {
+		name := strategy.Name
+		override, _ := perStrategy[name].(map[string]interface{})
+		constraints.limits[name] = constraintLimits(override, global)
+	}
+	return constraints
+}
+
+func constraintLimits(setting map[string]interface{}, defaults map[string]float64) map[string]float64 {
+	limits := make(map[string]float64)
+	for key, value := range defaults {
+		limits[key] = value
+	}
+	for _, key := range constraintKeys {
+		if value, ok := setting[key].(float64); ok {
+			limits[key] = value
+		}
+	}
+	return limits
+}
+
+// ValidateStrategyConstraints returns a warning for every "perStrategy" entry that names no
+// strategy of the catalog.
+func ValidateStrategyConstraints(settings map[string]interface{}) []string {
+	setting, _ := settings["strategyConstraints"].(map[string]interface{})
+	perStrategy, _ := setting["perStrategy"].(map[string]interface{})
+	known := make(map[string]bool)
+	for _, strategy := range Strategies {
+		known[strategy.Name] = true
+	}
+	var warnings []string
+	for name := range perStrategy {
+		if !known[name] {
+			warnings = append(warnings, fmt.Sprintf("Strategy constraints for unknown strategy %q are ignored.", name))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// datasetYears is the time span of the trades in years, 0 without dated trades.
+func datasetYears(trades []Trade) float64 {
+	first, last := 0, 0
+	for _, trade := range trades {
+		key := trade.Int("Date_Key")
+		if key == 0 {
+			continue
+		}
+		if first == 0 || key < first {
+			first = key
+		}
+		if key > last {
+			last = key
+		}
+	}
+	if first == 0 {
+		return 0
+	}
+	days := utils.DateFromKey(last).Sub(utils.DateFromKey(first)).Hours()/24 + 1
+	return days / 365.25
+}
+
+// reject returns the first constraint the strategy's metrics break, or "".
+func (c StrategyConstraints) reject(strategyName string, metrics StrategyMetrics) string {
+	limits := c.limits[strategyName]
+	if len(limits) == 0 {
+		return ""
+	}
+	trades := float64(metrics.TotalTradesThisStrategy)
+	if limit := limits[rejectMinTrades]; limit > 0 && trades < limit {
+		return rejectMinTrades
+	}
+	if limit := limits[rejectMinTradesPerYear]; limit > 0 && c.years > 0 && trades/c.years < limit {
+		return rejectMinTradesPerYear
+	}
+	if limit := limits[rejectMinWinRate]; limit > 0 && metrics.WinRate*100 < limit {
+		return rejectMinWinRate
+	}
+	if limit := limits[rejectMinProfitFactor]; limit > 0 && metrics.ProfitFactor < limit {
+		return rejectMinProfitFactor
+	}
+	if limit := limits[rejectMaxDrawdown]; limit > 0 && metrics.MaxDrawdown > limit {
+		return rejectMaxDrawdown
+	}
+	return ""
+}
+
+// RejectionCounts counts why evaluations were discarded: combinations on a dataset by
+// reason, and strategies rejected by their constraints by strategy name and reason.
+type RejectionCounts struct {
+	Combinations map[string]int            `json:"combinations"`
+	Strategies   map[string]map[string]int `json:"strategies"`
+}
+
+// Reasons a combination is discarded on a dataset.
+const (
+	rejectMinTradeCount   = "minTradeCount"
+	rejectAllStrategies   = "minWinRateOrProfitFactor"
+	rejectNoScoreStrategy = "noScoredStrategy"
+)
+
+// addCombination counts discarded combinations; a nil RejectionCounts counts nothing.
+func (c *RejectionCounts) addCombination(reason string, count int) {
+	if c == nil {
+		return
+	}
+	if c.Combinations == nil {
+		c.Combinations = make(map[string]int)
+	}
+	c.Combinations[reason] += count
+}
+
+// addStrategy counts rejected strategies; a nil RejectionCounts counts nothing.
+func (c *RejectionCounts) addStrategy(name, reason string, count int) {
+	if c == nil {
+		return
+	}
+	if c.Strategies == nil {
+		c.Strategies = make(map[string]map[string]int)
+	}
+	if c.Strategies[name] == nil {
+		c.Strategies[name] = make(map[string]int)
+	}
+	c.Strategies[name][reason] += count
+}
+
+// RejectionCollector merges the rejection counts of all workers when they finish.
+type RejectionCollector struct {
+	mu     sync.Mutex
+	counts RejectionCounts
+}
+
+func (r *RejectionCollector) merge(counts *RejectionCounts) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for reason, count := range counts.Combinations {
+		r.counts.addCombination(reason, count)
+	}
+	for name, reasons := range counts.Strategies {
+		for reason, count := range reasons {
+			r.counts.addStrategy(name, reason, count)
+		}
+	}
+}
+
+// Counts returns the merged rejection counts; the maps are empty, not nil, without rejections.
+func (r *RejectionCollector) Counts() RejectionCounts {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := r.counts
+	if counts.Combinations == nil {
+		counts.Combinations = map[string]int{}
+	}
+	if counts.Strategies == nil {
+		counts.Strategies = map[string]map[string]int{}
+	}
+	return counts
+}
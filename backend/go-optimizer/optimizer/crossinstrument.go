@@ -0,0 +1,113 @@
+package optimizer
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+)
+
+// CrossInstrumentResult combines the results of one combination on every dataset into a
+// "works across instruments" result. Its scores are the worst dataset score, or the
+// average with mode "average". A strategy that cannot be scored on one dataset cannot
+// be scored across them.
+func CrossInstrumentResult(combo Combination, results []Result, datasets []Dataset, mode string) (Result, bool) {
+	aggregate := func(values []float64) float64 {
+		combined := 0.0
+		for i, value := range values {
+			if math.IsInf(value, 0) {
+				return math.Inf(-1)
+			}
+			switch {
+			case mode == "average":
+				combined += value / float64(len(values))
+			case i == 0 || value < combined:
+				combined = value
+			}
+		}
+		return combined
+	}
+
+	cross := Result{
+		Combination:      combo,
+		Metrics:          map[string]StrategyMetrics{},
+		StrategyScores:   make(map[string]float64),
+		InstrumentScores: make(map[string]float64),
+	}
+	hash := fnv.New64a()
+	var overallScores []float64
+	strategyScores := make(map[string][]float64)
+	for i, result := range results {
+		overallScores = append(overallScores, result.OverallScore)
+		cross.InstrumentScores[datasets[i].Label()] = result.OverallScore
+		cross.OverallTradeCount += result.OverallTradeCount
+		for _, strategy := range Strategies {
+			name := strategy.Name
+			score, ok := result.StrategyScores[name]
+			if !ok {
+				score = math.Inf(-1)
+			}
+			strategyScores[name] = append(strategyScores[name], score)
+		}
+		hash.Write([]byte(strconv.FormatUint(result.TradeSetHash, 16)))
+	}
+
+	for name, scores := range strategyScores {
+		cross.StrategyScores[name] = aggregate(scores)
+	}
+	cross.OverallScore = aggregate(overallScores)
+	cross.TradeSetHash = hash.Sum64()
+	return cross, !math.IsInf(cross.OverallScore, 0)
+}
+
+// ProcessFinalResultsPerDataset ranks the results of every dataset on their own, followed
+// by the cross-instrument ranking. A single dataset is ranked exactly as before. A
+// maxOverlap in (0, 1) diversifies the per-dataset rankings: a candidate sharing more than
+// that fraction of its trades (Jaccard) with a better-ranked result of the same strategy
+// is skipped. The cross-instrument ranking is not diversified: its results span datasets
+// whose trades share no positions to compare, so it only collapses equal trade sets.
+func ProcessFinalResultsPerDataset(rawResults []Result, datasets []Dataset, groupColumns []string, maxOverlap float64) []Result {
+	rank := func(results []Result, trades []Trade) []Result {
+		overlap := newOverlapFilter(trades, maxOverlap)
+		if len(groupColumns) > 0 {
+			return processFinalResultsPerGroup(results, groupColumns, overlap)
+		}
+		return processFinalResults(results, overlap)
+	}
+	if len(datasets) == 1 {
+		return rank(rawResults, datasets[0].Trades)
+	}
+
+	byDataset := make(map[string][]Result)
+	var crossResults []Result
+	for _, result := range rawResults {
+		if result.InstrumentScores != nil {
+			crossResults = append(crossResults, result)
+			continue
+		}
+		label := Dataset{Instrument: result.Instrument, Timeframe: result.Timeframe}.Label()
+		byDataset[label] = append(byDataset[label], result)
+	}
+
+	finalResults := []Result{}
+	for _, dataset := range datasets {
+		finalResults = append(finalResults, rank(byDataset[dataset.Label()], dataset.Trades)...)
+	}
+	return append(finalResults, rank(crossResults, nil)...)
+}
+
+// resultTrades returns the prepared trades of the dataset a result was computed on, or
+// nil for a cross-instrument result.
+func resultTrades(result Result, datasets []Dataset) []Trade {
+	if result.InstrumentScores != nil {
+		return nil
+	}
+	if len(datasets) == 1 {
+		return datasets[0].Trades
+	}
+	for _, dataset := range datasets {
+		if dataset.Instrument == result.Instrument && dataset.Timeframe == result.Timeframe {
+			return dataset.Trades
+		}
+	}
+	return nil
+}
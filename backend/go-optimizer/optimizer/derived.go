@@ -0,0 +1,39 @@
+package optimizer
+
+// DerivedInputs carries the data besides the trade table that derived columns are computed from.
+type DerivedInputs struct {
+	Instrument string
+	News       []NewsEvent
+	Holidays   []HolidayOverride
+}
+
+// AddDerivedColumns adds the columns computed from the loaded trade columns, so criteria
+// and predefined filters can address them by name like any database column. A feature
+// whose source columns are missing is skipped with a warning; the criteria that depend
+// on it are then removed by ValidateEnabledCriteria.
+func AddDerivedColumns(table *TradeTable, settings map[string]interface{}, inputs DerivedInputs) []string {
+	var warnings []string
+
+	if err := addCalendarColumns(table); err != nil {
+		warnings = append(warnings, "Calendar columns unavailable: "+err.Error())
+	}
+	holidays, err := addHolidayColumns(table, inputs.Instrument, inputs.Holidays, settings)
+	if err != nil {
+		warnings = append(warnings, "Holiday columns unavailable: "+err.Error())
+	}
+	// The first and last trading day of a month depend on the holidays.
+	if table.HasColumn("Date_Key") {
+		addTradingDayColumns(table, holidays)
+	}
+	if err := addMarketTimeColumns(table, settings); err != nil {
+		warnings = append(warnings, "Market time columns unavailable: "+err.Error())
+	}
+	if err := addSessionColumns(table); err != nil {
+		warnings = append(warnings, "Session columns unavailable: "+err.Error())
+	}
+	if err := addNewsColumns(table, inputs.News, inputs.Instrument, settings); err != nil {
+		warnings = append(warnings, "News columns unavailable: "+err.Error())
+	}
+
+	return warnings
+}
@@ -0,0 +1,23 @@
+package optimizer
+
+// directionCriterion is the trade column holding BUY or SELL.
+const directionCriterion = "Direction"
+
+var tradeDirections = []string{"BUY", "SELL"}
+
+// SplitDirectionCriteria makes every combination direction-specific for the
+// "splitDirections" setting: the Direction criterion tests BUY and SELL but no "any".
+func SplitDirectionCriteria(criteria []CombinationCriterion) []CombinationCriterion {
+	split := CombinationCriterion{ColumnHeader: directionCriterion, Type: "exact", TestValues: []interface{}{}, Thresholds: []interface{}{}}
+	for _, direction := range tradeDirections {
+		split.TestValues = append(split.TestValues, direction)
+	}
+
+	result := []CombinationCriterion{split}
+	for _, criterion := range criteria {
+		if criterion.ColumnHeader != directionCriterion {
+			result = append(result, criterion)
+		}
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package optimizer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// entryDelayColumn records how many seconds after the signal the pending order filled.
+const entryDelayColumn = "Entered_After_Seconds"
+
+// defaultEntryDelayBuckets are the bucket bounds in seconds. The buckets below the first and
+// above the last bound are open.
+var defaultEntryDelayBuckets = []float64{0, 30, 60, 120, 300, 600, 1800}
+
+// HistogramBucket counts the trades with a value in [Min, Max). A nil Min or Max is unbounded.
+type HistogramBucket struct {
+	Min    *float64 `json:"min"`
+	Max    *float64 `json:"max"`
+	Trades int      `json:"trades"`
+}
+
+// ValidateEntryDelayBuckets checks the "entryDelayBuckets" setting.
+func ValidateEntryDelayBuckets(settings map[string]interface{}) error {
+	_, err := entryDelayBuckets(settings)
+	return err
+}
+
+// entryDelayBuckets returns the configured bucket bounds in ascending order, or the defaults.
+func entryDelayBuckets(settings map[string]interface{}) ([]float64, error) {
+	setting, ok := settings["entryDelayBuckets"]
+	if !ok || setting == nil {
+		return defaultEntryDelayBuckets, nil
+	}
+	configured, ok := setting.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("'entryDelayBuckets' setting is not a list of seconds")
+	}
+	if len(configured) == 0 {
+		return defaultEntryDelayBuckets, nil
+	}
+	bounds := make([]float64, 0, len(configured))
+	for _, bound := range configured {
+		value, ok := bound.(float64)
+		if !ok {
+			return nil, fmt.Errorf("entry delay bucket bound %v is not a number", bound)
+		}
+		bounds = append(bounds, value)
+	}
+	sort.Float64s(bounds)
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] == bounds[i-1] {
+			return nil, fmt.Errorf("entry delay bucket bound %g is listed twice", bounds[i])
+		}
+	}
+	return bounds, nil
+}
+
+// AttachEntryDelayHistograms re-applies each final result's combination to its dataset and
+// adds the distribution of Entered_After_Seconds over its trades. Cross-instrument results
+// get none. The bucket bounds can be set with the "entryDelayBuckets" setting, which
+// ValidateEntryDelayBuckets has checked.
+func AttachEntryDelayHistograms(results []Result, datasets []Dataset, settings map[string]interface{}) {
+
+	bounds, err := entryDelayBuckets(settings)
+	if err != nil {
+		bounds = defaultEntryDelayBuckets
+	}
+
+	for i := range results {
+		trades := resultTrades(results[i], datasets)
+		if len(trades) == 0 || trades[0].Column(entryDelayColumn) == nil {
+			continue
+		}
+		filteredTrades, _, _ := ApplyFilters(trades, results[i].Combination)
+		results[i].EntryDelayHistogram = entryDelayHistogram(filteredTrades, bounds)
+	}
+}
+
+// entryDelayHistogram counts the trades between each pair of ascending bounds, plus the
+// open buckets below the first and above the last bound.
+func entryDelayHistogram(trades []Trade, bounds []float64) []HistogramBucket {
+	buckets := make([]HistogramBucket, len(bounds)+1)
+	for i := range bounds {
+		bound := bounds[i]
+		buckets[i].Max = &bound
+		buckets[i+1].Min = &bound
+	}
+
+	for _, trade := range trades {
+		delay := trade.Float(entryDelayColumn)
+		// The bucket index is the number of bounds at or below the delay.
+		index := sort.Search(len(bounds), func(i int) bool { return bounds[i] > delay })
+		buckets[index].Trades++
+	}
+	return buckets
+}
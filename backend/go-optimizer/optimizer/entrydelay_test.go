@@ -0,0 +1,83 @@
+package optimizer
+
+import "testing"
+
+func TestEntryDelayBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting interface{}
+		want    []float64
+		wantErr bool
+	}{
+		{"default", nil, defaultEntryDelayBuckets, false},
+		{"empty list uses the default", []interface{}{}, defaultEntryDelayBuckets, false},
+		{"sorted", []interface{}{60.0, 0.0, 30.0}, []float64{0, 30, 60}, false},
+		{"duplicate bound", []interface{}{30.0, 0.0, 30.0}, nil, true},
+		{"not a number", []interface{}{"30"}, nil, true},
+		{"not a list", 30.0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := map[string]interface{}{}
+			if tt.setting != nil {
+				settings["entryDelayBuckets"] = tt.setting
+			}
+			bounds, err := entryDelayBuckets(settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(bounds) != len(tt.want) {
+				t.Fatalf("bounds = %v, want %v", bounds, tt.want)
+			}
+			for i := range bounds {
+				if bounds[i] != tt.want[i] {
+					t.Errorf("bounds = %v, want %v", bounds, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEntryDelayHistogram(t *testing.T) {
+	trades := testTrades(t, []testColumn{{entryDelayColumn, FloatColumn}},
+		[]interface{}{-5.0}, []interface{}{0.0}, []interface{}{29.0}, []interface{}{30.0}, []interface{}{600.0},
+	)
+	buckets := entryDelayHistogram(trades, []float64{0, 30, 60})
+
+	want := []struct {
+		min, max *float64
+		trades   int
+	}{
+		{nil, floatPointer(0), 1},
+		{floatPointer(0), floatPointer(30), 2},
+		{floatPointer(30), floatPointer(60), 1},
+		{floatPointer(60), nil, 1},
+	}
+	if len(buckets) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(buckets), len(want))
+	}
+	for i, bucket := range buckets {
+		if !sameBound(bucket.Min, want[i].min) || !sameBound(bucket.Max, want[i].max) || bucket.Trades != want[i].trades {
+			t.Errorf("bucket %d = [%v, %v) with %d trades, want [%v, %v) with %d", i,
+				boundString(bucket.Min), boundString(bucket.Max), bucket.Trades, boundString(want[i].min), boundString(want[i].max), want[i].trades)
+		}
+	}
+}
+
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+func sameBound(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func boundString(bound *float64) interface{} {
+	if bound == nil {
+		return "open"
+	}
+	return *bound
+}
@@ -0,0 +1,94 @@
+package optimizer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// maxListedAliases caps how many equivalent combinations are listed on a representative.
+// The full size of the class is still reported through AliasCount.
+const maxListedAliases = 25
+
+// TradeSetHash builds the equivalence key of a filtered trade set. Two combinations that
+// select exactly the same trades produce the same metrics, unless they also change how
+// the metrics are calculated (LTA strategies or the candle size / TP ratio), so those
+// inputs are part of the key as well.
+func TradeSetHash(trades []Trade, ltaCombination bool, candleSizeTpRatio float64) uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+
+	for _, trade := range trades {
+		binary.LittleEndian.PutUint64(buf, uint64(trade.ID()))
+		h.Write(buf)
+	}
+
+	if ltaCombination {
+		h.Write([]byte{1})
+	} else {
+		h.Write([]byte{0})
+	}
+	binary.LittleEndian.PutUint64(buf, math.Float64bits(candleSizeTpRatio))
+	h.Write(buf)
+
+	return h.Sum64()
+}
+
+// isPreferredRepresentative reports whether combination a should represent its
+// equivalence class instead of b. The simplest combination (fewest criteria) wins;
+// among equally simple ones the tieBreakerKeys policy picks the widest ranges.
+func isPreferredRepresentative(a, b Combination) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+
+	if c := compareTieBreakers(a, b); c != 0 {
+		return c > 0
+	}
+
+	aBytes, _ := json.Marshal(a)
+	bBytes, _ := json.Marshal(b)
+	return string(aBytes) < string(bBytes)
+}
+
+// CollapseEquivalentResults keeps one canonical result per filtered trade set and lists
+// the other combinations of the class as its aliases.
+func CollapseEquivalentResults(rawResults []Result) []Result {
+	classes := make(map[uint64][]int)
+	var order []uint64
+
+	for i, res := range rawResults {
+		if _, seen := classes[res.TradeSetHash]; !seen {
+			order = append(order, res.TradeSetHash)
+		}
+		classes[res.TradeSetHash] = append(classes[res.TradeSetHash], i)
+	}
+
+	if len(order) == len(rawResults) {
+		return rawResults
+	}
+
+	collapsed := make([]Result, 0, len(order))
+	for _, hash := range order {
+		members := classes[hash]
+		sort.Slice(members, func(i, j int) bool {
+			return isPreferredRepresentative(rawResults[members[i]].Combination, rawResults[members[j]].Combination)
+		})
+
+		representative := rawResults[members[0]]
+		representative.AliasCount = len(members) - 1
+		representative.Aliases = nil
+		for _, idx := range members[1:] {
+			if len(representative.Aliases) >= maxListedAliases {
+				break
+			}
+			representative.Aliases = append(representative.Aliases, rawResults[idx].Combination)
+		}
+		collapsed = append(collapsed, representative)
+	}
+
+	debugLog.Printf("Collapsed %d results into %d equivalence classes.", len(rawResults), len(collapsed))
+	return collapsed
+}
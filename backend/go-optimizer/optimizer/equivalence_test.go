@@ -0,0 +1,117 @@
+package optimizer
+
+import "testing"
+
+func TestTradeSetHash(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"id", IntColumn}},
+		[]interface{}{1}, []interface{}{2}, []interface{}{3},
+	)
+	base := TradeSetHash(trades[:2], false, 0)
+	tests := []struct {
+		name  string
+		hash  uint64
+		equal bool
+	}{
+		{"same trades", TradeSetHash(trades[:2], false, 0), true},
+		{"other trades", TradeSetHash(trades[1:], false, 0), false},
+		{"more trades", TradeSetHash(trades, false, 0), false},
+		{"LTA combination", TradeSetHash(trades[:2], true, 0), false},
+		{"candle size TP ratio", TradeSetHash(trades[:2], false, 1.5), false},
+	}
+	for _, tt := range tests {
+		if (tt.hash == base) != tt.equal {
+			t.Errorf("%s: hash equal = %v, want %v", tt.name, tt.hash == base, tt.equal)
+		}
+	}
+}
+
+func TestIsPreferredRepresentative(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Combination
+		want bool
+	}{
+		{"fewer criteria", Combination{"Setup": "A"}, Combination{"Setup": "A", "Direction": "Long"}, true},
+		{"more criteria", Combination{"Setup": "A", "Direction": "Long"}, Combination{"Setup": "A"}, false},
+		{"wider range", Combination{"Candle_Size": map[string]float64{"min": 1, "max": 10}}, Combination{"Candle_Size": map[string]float64{"min": 2, "max": 10}}, true},
+		{"open range", Combination{"Candle_Size": map[string]float64{"min": 1}}, Combination{"Candle_Size": map[string]float64{"min": 1, "max": 10}}, true},
+		{"narrower excluded band", Combination{"Candle_Size": map[string]float64{"excludeMin": 2, "excludeMax": 3}}, Combination{"Candle_Size": map[string]float64{"excludeMin": 1, "excludeMax": 3}}, true},
+		{"tie broken by JSON", Combination{"Setup": "A"}, Combination{"Setup": "B"}, true},
+	}
+	for _, tt := range tests {
+		if got := isPreferredRepresentative(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: isPreferredRepresentative = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollapseEquivalentResults(t *testing.T) {
+	tests := []struct {
+		name           string
+		results        []Result
+		representative []Combination
+		aliasCounts    []int
+	}{
+		{
+			name: "no equivalent results",
+			results: []Result{
+				{Combination: Combination{"Setup": "A"}, TradeSetHash: 1},
+				{Combination: Combination{"Setup": "B"}, TradeSetHash: 2},
+			},
+			representative: []Combination{{"Setup": "A"}, {"Setup": "B"}},
+			aliasCounts:    []int{0, 0},
+		},
+		{
+			name: "simplest combination represents its class",
+			results: []Result{
+				{Combination: Combination{"Setup": "A", "Direction": "Long"}, TradeSetHash: 1},
+				{Combination: Combination{"Setup": "B"}, TradeSetHash: 2},
+				{Combination: Combination{"Setup": "A"}, TradeSetHash: 1},
+			},
+			representative: []Combination{{"Setup": "A"}, {"Setup": "B"}},
+			aliasCounts:    []int{1, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collapsed := CollapseEquivalentResults(tt.results)
+			if len(collapsed) != len(tt.representative) {
+				t.Fatalf("got %d results, want %d", len(collapsed), len(tt.representative))
+			}
+			for i, result := range collapsed {
+				if !isSameCombination(result.Combination, tt.representative[i]) {
+					t.Errorf("result %d = %v, want %v", i, result.Combination, tt.representative[i])
+				}
+				if result.AliasCount != tt.aliasCounts[i] || len(result.Aliases) != tt.aliasCounts[i] {
+					t.Errorf("result %d has %d aliases (%d listed), want %d", i, result.AliasCount, len(result.Aliases), tt.aliasCounts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCollapseEquivalentResultsCapsListedAliases(t *testing.T) {
+	var results []Result
+	for i := 0; i < maxListedAliases+5; i++ {
+		results = append(results, Result{Combination: Combination{"Setup": string(rune('A' + i))}, TradeSetHash: 1})
+	}
+	collapsed := CollapseEquivalentResults(results)
+	if len(collapsed) != 1 {
+		t.Fatalf("got %d results, want 1", len(collapsed))
+	}
+	if collapsed[0].AliasCount != maxListedAliases+4 || len(collapsed[0].Aliases) != maxListedAliases {
+		t.Errorf("alias count %d with %d listed, want %d with %d listed", collapsed[0].AliasCount, len(collapsed[0].Aliases), maxListedAliases+4, maxListedAliases)
+	}
+}
+
+func isSameCombination(a, b Combination) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if b[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+)
+
+// strategyLeg is one take-profit target of a strategy closing a fraction of the position.
+// A simple strategy is a single leg closing the whole position.
+type strategyLeg struct {
+	fraction            float64
+	winColumn           string
+	tpPipsColumn        string
+	rangeBreakoutColumn string
+}
+
+// strategyLegs returns the legs of a catalog strategy. A composite strategy lists them under
+// "legs", each with a "fraction" of the position and its own "winColumn", "tpPipsColumn"
+// and optional "rangeBreakoutColumn"; the fractions add up to 1. A simple strategy is read
+// from its own winColumn, tpPipsColumn and rangeBreakoutColumn.
+func strategyLegs(strategy map[string]interface{}) ([]strategyLeg, error) {
+	rawLegs, composite := strategy["legs"]
+	if !composite {
+		leg, err := newStrategyLeg(strategy, 1)
+		if err != nil {
+			return nil, err
+		}
+		return []strategyLeg{leg}, nil
+	}
+
+	list, ok := rawLegs.([]interface{})
+	if !ok || len(list) < 2 {
+		return nil, fmt.Errorf("legs must be a list of at least two legs")
+	}
+	var legs []strategyLeg
+	total := 0.0
+	for i, rawLeg := range list {
+		legMap, ok := rawLeg.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("leg %d is not an object", i)
+		}
+		fraction, _ := legMap["fraction"].(float64)
+		if fraction <= 0 || fraction > 1 {
+			return nil, fmt.Errorf("leg %d has an invalid fraction", i)
+		}
+		leg, err := newStrategyLeg(legMap, fraction)
+		if err != nil {
+			return nil, fmt.Errorf("leg %d: %w", i, err)
+		}
+		legs = append(legs, leg)
+		total += fraction
+	}
+	if math.Abs(total-1) > 1e-9 {
+		return nil, fmt.Errorf("leg fractions add up to %g instead of 1", total)
+	}
+	return legs, nil
+}
+
+func newStrategyLeg(definition map[string]interface{}, fraction float64) (strategyLeg, error) {
+	leg := strategyLeg{fraction: fraction}
+	leg.winColumn, _ = definition["winColumn"].(string)
+	leg.tpPipsColumn, _ = definition["tpPipsColumn"].(string)
+	leg.rangeBreakoutColumn, _ = definition["rangeBreakoutColumn"].(string)
+	if leg.winColumn == "" {
+		return strategyLeg{}, fmt.Errorf("has no winColumn")
+	}
+	if leg.tpPipsColumn == "" {
+		return strategyLeg{}, fmt.Errorf("has no tpPipsColumn")
+	}
+	return leg, nil
+}
+
+// breakEvenRule reads the optional "breakEvenLeg" of a composite strategy, the index of the
+// leg whose target moves the stop of the remaining position to break-even plus
+// "breakEvenOffsetPips". It returns -1 without a break-even rule.
+func breakEvenRule(strategy map[string]interface{}, legs []strategyLeg) (int, float64, error) {
+	offset, _ := strategy["breakEvenOffsetPips"].(float64)
+	var leg int
+	switch value := strategy["breakEvenLeg"].(type) {
+	case nil:
+		return -1, 0, nil
+	case int:
+		leg = value
+	case float64:
+		leg = int(value)
+		if float64(leg) != value {
+			return 0, 0, fmt.Errorf("breakEvenLeg must be a leg index")
+		}
+	default:
+		return 0, 0, fmt.Errorf("breakEvenLeg must be a leg index")
+	}
+	if leg < 0 || leg >= len(legs)-1 {
+		return 0, 0, fmt.Errorf("breakEvenLeg %d must name a leg before the last one", leg)
+	}
+	return leg, offset, nil
+}
+
+// target returns whether the leg's target was hit and its distance in pips, the SL distance
+// if the trade has none. ok is false if the leg has no usable target.
+func (leg strategyLeg) target(trade Trade, slPips float64) (isWin bool, tpPips float64, ok bool) {
+	isWin = trade.Bool(leg.winColumn)
+	tpPips = trade.Float(leg.tpPipsColumn)
+	if leg.rangeBreakoutColumn != "" {
+		if tpPips == 0 && !trade.Bool(leg.rangeBreakoutColumn) {
+			return false, 0, false
+		}
+	}
+	if tpPips == 0 {
+		tpPips = slPips
+	}
+	if tpPips < 1.0 {
+		return false, 0, false
+	}
+	return isWin, tpPips, true
+}
+
+// compositeOutcome returns the pips a composite strategy made on a trade. A leg that hit
+// its target earns its fraction of the target; a missed leg loses its fraction of the stop,
+// unless the break-even leg was hit first, in which case it closes at the break-even offset.
+// A leg with a target nearer than the break-even leg's is missed before the stop moves.
+// The outcome limits apply to the blended target, the fraction-weighted mean of the legs'.
+func compositeOutcome(trade Trade, legs []strategyLeg, breakEvenLeg int, breakEvenOffset, slPips float64, limits outcomeLimits) (float64, bool) {
+	wins := make([]bool, len(legs))
+	targets := make([]float64, len(legs))
+	blended := 0.0
+	for i, leg := range legs {
+		isWin, tpPips, ok := leg.target(trade, slPips)
+		if !ok {
+			return 0, false
+		}
+		wins[i], targets[i] = isWin, tpPips
+		blended += leg.fraction * tpPips
+	}
+	if !limits.allow(trade, blended, slPips) {
+		return 0, false
+	}
+
+	atBreakEven := breakEvenLeg >= 0 && wins[breakEvenLeg]
+	pips := 0.0
+	for i, leg := range legs {
+		switch {
+		case wins[i]:
+			pips += leg.fraction * targets[i]
+		case atBreakEven && targets[i] >= targets[breakEvenLeg]:
+			pips += leg.fraction * breakEvenOffset
+		default:
+			pips -= leg.fraction * slPips
+		}
+	}
+	return pips, true
+}
@@ -0,0 +1,81 @@
+package optimizer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompositeOutcome(t *testing.T) {
+	columns := []testColumn{{"Win_1", BoolColumn}, {"TP_1", FloatColumn}, {"Win_2", BoolColumn}, {"TP_2", FloatColumn}, {"Candle_Size", FloatColumn}}
+	legs := []strategyLeg{
+		{fraction: 0.5, winColumn: "Win_1", tpPipsColumn: "TP_1"},
+		{fraction: 0.5, winColumn: "Win_2", tpPipsColumn: "TP_2"},
+	}
+	const slPips = 10.0
+
+	tests := []struct {
+		name         string
+		row          []interface{}
+		breakEvenLeg int
+		offset       float64
+		limits       outcomeLimits
+		want         float64
+		ok           bool
+	}{
+		{"both targets hit", []interface{}{true, 10.0, true, 20.0, 5.0}, -1, 0, outcomeLimits{}, 15, true},
+		{"both targets missed", []interface{}{false, 10.0, false, 20.0, 5.0}, -1, 0, outcomeLimits{}, -10, true},
+		{"first target hit without break-even", []interface{}{true, 10.0, false, 20.0, 5.0}, -1, 0, outcomeLimits{}, 0, true},
+		{"first target hit with break-even", []interface{}{true, 10.0, false, 20.0, 5.0}, 0, 0, outcomeLimits{}, 5, true},
+		{"first target hit with break-even offset", []interface{}{true, 10.0, false, 20.0, 5.0}, 0, 1, outcomeLimits{}, 5.5, true},
+		{"nearer target missed before break-even", []interface{}{true, 10.0, false, 5.0, 5.0}, 0, 1, outcomeLimits{}, 0, true},
+		{"break-even leg missed", []interface{}{false, 10.0, true, 20.0, 5.0}, 0, 1, outcomeLimits{}, 5, true},
+		{"missing target uses the stop distance", []interface{}{true, 0.0, true, 20.0, 5.0}, -1, 0, outcomeLimits{}, 15, true},
+		{"target below one pip is not counted", []interface{}{true, 0.5, true, 20.0, 5.0}, -1, 0, outcomeLimits{}, 0, false},
+		{"limits apply to the blended target", []interface{}{true, 10.0, true, 40.0, 5.0}, -1, 0, outcomeLimits{maxTPToSLRatio: 3}, 25, true},
+		{"blended target beyond the TP to SL limit", []interface{}{true, 10.0, true, 40.0, 5.0}, -1, 0, outcomeLimits{maxTPToSLRatio: 2}, 0, false},
+		{"blended target below the SL to TP limit", []interface{}{true, 5.0, true, 10.0, 5.0}, -1, 0, outcomeLimits{minSLToTPRatio: 1}, 0, false},
+		{"blended target beyond the candle size limit", []interface{}{true, 10.0, true, 20.0, 5.0}, -1, 0, outcomeLimits{maxCandleSizeTPRatio: 2}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trade := testTrades(t, columns, tt.row)[0]
+			got, ok := compositeOutcome(trade, legs, tt.breakEvenLeg, tt.offset, slPips, tt.limits)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("pips = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrategyLegs(t *testing.T) {
+	leg := func(fraction float64, win string) map[string]interface{} {
+		return map[string]interface{}{"fraction": fraction, "winColumn": win, "tpPipsColumn": "TP_" + win}
+	}
+	tests := []struct {
+		name     string
+		strategy map[string]interface{}
+		legs     int
+		wantErr  bool
+	}{
+		{"simple strategy", map[string]interface{}{"winColumn": "W", "tpPipsColumn": "TP"}, 1, false},
+		{"simple strategy without target", map[string]interface{}{"winColumn": "W"}, 0, true},
+		{"composite strategy", map[string]interface{}{"legs": []interface{}{leg(0.5, "A"), leg(0.5, "B")}}, 2, false},
+		{"single leg", map[string]interface{}{"legs": []interface{}{leg(1, "A")}}, 0, true},
+		{"fractions not adding up", map[string]interface{}{"legs": []interface{}{leg(0.5, "A"), leg(0.4, "B")}}, 0, true},
+		{"leg without win column", map[string]interface{}{"legs": []interface{}{leg(0.5, "A"), leg(0.5, "")}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legs, err := strategyLegs(tt.strategy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(legs) != tt.legs {
+				t.Errorf("got %d legs, want %d", len(legs), tt.legs)
+			}
+		})
+	}
+}
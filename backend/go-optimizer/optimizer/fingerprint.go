@@ -0,0 +1,131 @@
+package optimizer
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// tradeBitset marks the trades of a dataset that a combination selects, by their position
+// in the dataset's prepared trades.
+type tradeBitset []uint64
+
+// membership returns the bitset of the selected trades. ApplyFilters keeps the order of
+// the dataset trades, so both slices are walked in step.
+func membership(datasetTrades, selected []Trade) tradeBitset {
+	set := make(tradeBitset, (len(datasetTrades)+63)/64)
+	j := 0
+	for i, trade := range datasetTrades {
+		if j < len(selected) && selected[j].Row == trade.Row {
+			set[i/64] |= 1 << (uint(i) % 64)
+			j++
+		}
+	}
+	return set
+}
+
+// jaccard returns |a ∩ b| / |a ∪ b|, 0 for two empty sets.
+func jaccard(a, b tradeBitset) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	intersection, union := 0, 0
+	for i := range a {
+		intersection += bits.OnesCount64(a[i] & b[i])
+		union += bits.OnesCount64(a[i] | b[i])
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(intersection) / float64(union)
+}
+
+// fingerprint is a compact hash of the bitset, in hex: results with equal fingerprints take
+// the same trades of a dataset. How much different trade sets overlap is reported by the
+// overlap matrix; the trades themselves come with the trade details.
+func (s tradeBitset) fingerprint() string {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, word := range s {
+		binary.LittleEndian.PutUint64(buf, word)
+		h.Write(buf)
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// overlapScanFactor caps how many ranked candidates the overlap filter compares per
+// strategy, as a multiple of the results kept; the rest of a long ranking is dropped.
+const overlapScanFactor = 10
+
+// overlapFilter diversifies a ranking: a candidate is skipped if it shares more than
+// maxOverlap (Jaccard) of its trades with a result already selected.
+type overlapFilter struct {
+	trades     []Trade
+	maxOverlap float64
+	// cache holds the bitsets by TradeSetHash: results of equal hash select the same trades.
+	cache map[uint64]tradeBitset
+}
+
+// newOverlapFilter returns nil, no diversification, without trades or for a maxOverlap
+// outside (0, 1).
+func newOverlapFilter(trades []Trade, maxOverlap float64) *overlapFilter {
+	if trades == nil || maxOverlap <= 0 || maxOverlap >= 1 {
+		return nil
+	}
+	return &overlapFilter{trades: trades, maxOverlap: maxOverlap, cache: make(map[uint64]tradeBitset)}
+}
+
+func (f *overlapFilter) membership(result Result) tradeBitset {
+	if set, ok := f.cache[result.TradeSetHash]; ok {
+		return set
+	}
+	selected, _, _ := ApplyFilters(f.trades, result.Combination)
+	set := membership(f.trades, selected)
+	f.cache[result.TradeSetHash] = set
+	return set
+}
+
+// overlaps reports whether the candidate overlaps too much with any of the selected results.
+func (f *overlapFilter) overlaps(candidate Result, selected []Result) bool {
+	candidateSet := f.membership(candidate)
+	for _, result := range selected {
+		if jaccard(candidateSet, f.membership(result)) > f.maxOverlap {
+			return true
+		}
+	}
+	return false
+}
+
+// AttachFingerprints sets the trade-membership fingerprint of every final result and
+// returns the Jaccard overlap matrix among them, in result order. Results on different
+// datasets and cross-instrument results do not overlap.
+func AttachFingerprints(results []Result, datasets []Dataset) [][]float64 {
+	sets := make([]tradeBitset, len(results))
+	for i := range results {
+		trades := resultTrades(results[i], datasets)
+		if trades == nil {
+			continue
+		}
+		selected, _, _ := ApplyFilters(trades, results[i].Combination)
+		sets[i] = membership(trades, selected)
+		results[i].Fingerprint = sets[i].fingerprint()
+	}
+
+	matrix := make([][]float64, len(results))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(results))
+		for j := range matrix[i] {
+			switch {
+			case i == j:
+				matrix[i][j] = 1
+			case j < i:
+				matrix[i][j] = matrix[j][i]
+			case sets[i] != nil && sets[j] != nil && results[i].Instrument == results[j].Instrument && results[i].Timeframe == results[j].Timeframe:
+				matrix[i][j] = math.Round(jaccard(sets[i], sets[j])*1000) / 1000
+			}
+		}
+	}
+	return matrix
+}
@@ -0,0 +1,62 @@
+package optimizer
+
+import "testing"
+
+func TestJaccard(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b tradeBitset
+		want float64
+	}{
+		{"equal", tradeBitset{0b1011}, tradeBitset{0b1011}, 1},
+		{"disjoint", tradeBitset{0b0011}, tradeBitset{0b1100}, 0},
+		{"half", tradeBitset{0b0011}, tradeBitset{0b0110}, 1.0 / 3},
+		{"empty", tradeBitset{0}, tradeBitset{0}, 0},
+		{"across words", tradeBitset{1, 1}, tradeBitset{1, 0}, 0.5},
+		{"different datasets", tradeBitset{1}, tradeBitset{1, 0}, 0},
+	}
+	for _, tt := range tests {
+		if got := jaccard(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: jaccard = %g, want %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMembershipAndFingerprint(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"id", IntColumn}, {"Setup", StringColumn}},
+		[]interface{}{1, "A"}, []interface{}{2, "B"}, []interface{}{3, "A"},
+	)
+	setA := membership(trades, []Trade{trades[0], trades[2]})
+	if len(setA) != 1 || setA[0] != 0b101 {
+		t.Fatalf("membership = %b, want 101", setA)
+	}
+	setB := membership(trades, []Trade{trades[1]})
+	if setA.fingerprint() == setB.fingerprint() {
+		t.Errorf("different trade sets have the same fingerprint")
+	}
+	if setA.fingerprint() != membership(trades, []Trade{trades[0], trades[2]}).fingerprint() {
+		t.Errorf("equal trade sets have different fingerprints")
+	}
+	if len(setA.fingerprint()) > 16 {
+		t.Errorf("fingerprint %q is longer than a 64-bit hash", setA.fingerprint())
+	}
+}
+
+func TestNewOverlapFilter(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"id", IntColumn}}, []interface{}{1})
+	tests := []struct {
+		trades     []Trade
+		maxOverlap float64
+		enabled    bool
+	}{
+		{trades, 0.5, true},
+		{nil, 0.5, false},
+		{trades, 0, false},
+		{trades, 1, false},
+	}
+	for _, tt := range tests {
+		if got := newOverlapFilter(tt.trades, tt.maxOverlap) != nil; got != tt.enabled {
+			t.Errorf("newOverlapFilter(%d trades, %g) enabled = %v, want %v", len(tt.trades), tt.maxOverlap, got, tt.enabled)
+		}
+	}
+}
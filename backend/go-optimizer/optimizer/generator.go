@@ -56,6 +56,15 @@ func GenerateTimeWindows(baseMin, baseMax int, minShiftHours, maxShiftHours floa
 	return timeWindows
 }
 
+// thresholdToJSONNumber converts a threshold to the json.Number form expected by
+// generateNumericRanges. A nil threshold becomes "null", the "any" choice.
+func thresholdToJSONNumber(t interface{}) json.Number {
+	if t == nil {
+		return json.Number("null")
+	}
+	return json.Number(fmt.Sprintf("%v", t))
+}
+
 // --- Helper function to generate numeric ranges, ported from JS ---
 func generateNumericRanges(thresholds []json.Number, mode string) []interface{} {
 	var ranges []interface{}
@@ -90,10 +99,34 @@ func generateNumericRanges(thresholds []json.Number, mode string) []interface{}
 				ranges = append(ranges, map[string]float64{"min": sortedThresholds[i], "max": sortedThresholds[j]})
 			}
 		}
+	case "PERMUTATION_OPEN":
+		// All closed ranges plus the open-ended ranges on both sides.
+		for i := 0; i < len(sortedThresholds); i++ {
+			for j := i + 1; j < len(sortedThresholds); j++ {
+				ranges = append(ranges, map[string]float64{"min": sortedThresholds[i], "max": sortedThresholds[j]})
+			}
+		}
+		for _, t := range sortedThresholds {
+			ranges = append(ranges, map[string]float64{"min": t})
+		}
+		for _, t := range sortedThresholds {
+			ranges = append(ranges, map[string]float64{"max": t})
+		}
 	case "MAX":
 		for _, t := range sortedThresholds {
 			ranges = append(ranges, map[string]float64{"max": t})
 		}
+	case "MIN":
+		for _, t := range sortedThresholds {
+			ranges = append(ranges, map[string]float64{"min": t})
+		}
+	case "OUTSIDE":
+		// Exclusion bands: keep only trades below the first or above the second threshold.
+		for i := 0; i < len(sortedThresholds); i++ {
+			for j := i + 1; j < len(sortedThresholds); j++ {
+				ranges = append(ranges, map[string]float64{"excludeMin": sortedThresholds[i], "excludeMax": sortedThresholds[j]})
+			}
+		}
 	default: // Default range generation
 		ranges = append(ranges, map[string]float64{"max": sortedThresholds[0]})
 		for i := 0; i < len(sortedThresholds)-1; i++ {
@@ -125,9 +158,13 @@ func GenerateCombinations(criteria []CombinationCriterion) []Combination {
 			var jsonNumThresholds []json.Number
 			for _, t := range criterion.Thresholds {
 				// The JSON unmarshaler might put floats into json.Number strings
-				jsonNumThresholds = append(jsonNumThresholds, json.Number(fmt.Sprintf("%v", t)))
+				jsonNumThresholds = append(jsonNumThresholds, thresholdToJSONNumber(t))
 			}
 			effectiveTestValues = generateNumericRanges(jsonNumThresholds, criterion.Mode)
+			if len(effectiveTestValues) == 0 {
+				// A criterion without any range is a pass-through, as in CalculateTotalCombinations.
+				effectiveTestValues = []interface{}{nil}
+			}
 		} else { // 'exact' type
 			if len(criterion.TestValues) > 0 {
 				effectiveTestValues = criterion.TestValues
@@ -268,9 +305,13 @@ func getEffectiveTestValues(criterion CombinationCriterion) []interface{} {
 	if criterion.Type == "numericRange" {
 		var jsonNumThresholds []json.Number
 		for _, t := range criterion.Thresholds {
-			jsonNumThresholds = append(jsonNumThresholds, json.Number(fmt.Sprintf("%v", t)))
+			jsonNumThresholds = append(jsonNumThresholds, thresholdToJSONNumber(t))
+		}
+		if ranges := generateNumericRanges(jsonNumThresholds, criterion.Mode); len(ranges) > 0 {
+			return ranges
 		}
-		return generateNumericRanges(jsonNumThresholds, criterion.Mode)
+		// No range: the criterion passes every combination through unchanged.
+		return []interface{}{nil}
 	}
 	// 'exact' type
 	if len(criterion.TestValues) > 0 {
@@ -283,11 +324,8 @@ func getEffectiveTestValues(criterion CombinationCriterion) []interface{} {
 // generated by the streaming pipeline without actually creating them. This is very fast
 // and is used for progress reporting.
 func CalculateTotalCombinations(criteria []CombinationCriterion, timeWindowVariations []map[string]int, timeShiftEnabled bool) int {
-	if len(criteria) == 0 {
-		return 0
-	}
-
-	// Start with 1, as we will be multiplying.
+	// Start with 1, as we will be multiplying. Without criteria the generator still sends
+	// the empty combination, which the workers evaluate like any other.
 	totalBaseCombinations := 1
 
 	for _, criterion := range criteria {
@@ -322,7 +360,13 @@ func CalculateTotalCombinations(criteria []CombinationCriterion, timeWindowVaria
 					// This is the formula for "n choose 2", i.e., the number of pairs.
 					// n * (n - 1) / 2
 					choicesForThisCriterion += (numThresholds * (numThresholds - 1)) / 2
-				case "MAX":
+				case "PERMUTATION_OPEN":
+					// All pairs plus one open-ended range per threshold on each side.
+					choicesForThisCriterion += (numThresholds*(numThresholds-1))/2 + 2*numThresholds
+				case "OUTSIDE":
+					// One exclusion band per pair of thresholds.
+					choicesForThisCriterion += (numThresholds * (numThresholds - 1)) / 2
+				case "MAX", "MIN":
 					// One choice for each threshold.
 					choicesForThisCriterion += numThresholds
 				default:
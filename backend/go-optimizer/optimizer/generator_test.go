@@ -0,0 +1,101 @@
+package optimizer
+
+import "testing"
+
+func TestCalculateTotalCombinationsMatchesGenerator(t *testing.T) {
+	numeric := func(mode string, thresholds ...interface{}) CombinationCriterion {
+		return CombinationCriterion{ColumnHeader: "Candle_Size", Type: "numericRange", Mode: mode, Thresholds: thresholds}
+	}
+	exact := func(values ...interface{}) CombinationCriterion {
+		return CombinationCriterion{ColumnHeader: "Setup", Type: "exact", TestValues: values}
+	}
+	windows := []map[string]int{{"minMinutes": 0, "maxMinutes": 60}, {"minMinutes": 15, "maxMinutes": 60}}
+
+	tests := []struct {
+		name     string
+		criteria []CombinationCriterion
+		windows  []map[string]int
+	}{
+		{"no criteria", nil, nil},
+		{"no criteria with time windows", nil, windows},
+		{"default", []CombinationCriterion{numeric("", 1.0, 2.0, 3.0)}, nil},
+		{"default with any", []CombinationCriterion{numeric("", nil, 1.0, 2.0, 3.0)}, nil},
+		{"permutation", []CombinationCriterion{numeric("PERMUTATION", nil, 1.0, 2.0, 3.0, 4.0)}, nil},
+		{"permutation of one threshold", []CombinationCriterion{numeric("PERMUTATION", 1.0)}, nil},
+		{"permutation open", []CombinationCriterion{numeric("PERMUTATION_OPEN", nil, 1.0, 2.0, 3.0)}, nil},
+		{"outside", []CombinationCriterion{numeric("OUTSIDE", 1.0, 2.0, 3.0)}, nil},
+		{"max", []CombinationCriterion{numeric("MAX", nil, 1.0, 2.0)}, nil},
+		{"min", []CombinationCriterion{numeric("MIN", 1.0, 2.0)}, nil},
+		{"only any", []CombinationCriterion{numeric("MIN", nil)}, nil},
+		{"no thresholds", []CombinationCriterion{numeric("MIN"), exact("A", "B")}, nil},
+		{"exact", []CombinationCriterion{exact(nil, "A", "B")}, nil},
+		{"exact without values", []CombinationCriterion{exact()}, nil},
+		{"mixed with time windows", []CombinationCriterion{
+			numeric("PERMUTATION_OPEN", nil, 1.0, 2.0),
+			numeric("OUTSIDE", 1.0, 2.0, 3.0),
+			exact(nil, "A", "B"),
+			numeric("", 5.0),
+		}, windows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.criteria {
+				tt.criteria[i].ColumnHeader = tt.criteria[i].ColumnHeader + string(rune('A'+i))
+			}
+
+			baseCombos := make(chan Combination)
+			go func() {
+				GenerateBaseCombinationsRecursive(tt.criteria, 0, Combination{}, baseCombos)
+				close(baseCombos)
+			}()
+			streamed := 0
+			for range baseCombos {
+				streamed++
+			}
+			if listed := len(GenerateCombinations(tt.criteria)); listed != streamed {
+				t.Fatalf("GenerateCombinations made %d combinations, the stream %d", listed, streamed)
+			}
+			if len(tt.windows) > 0 {
+				streamed *= len(tt.windows)
+			}
+
+			if got := CalculateTotalCombinations(tt.criteria, tt.windows, len(tt.windows) > 0); got != streamed {
+				t.Errorf("CalculateTotalCombinations = %d, generated %d", got, streamed)
+			}
+		})
+	}
+}
+
+func TestCriterionWithoutRangesPassesThrough(t *testing.T) {
+	criteria := []CombinationCriterion{
+		{ColumnHeader: "Candle_Size", Type: "numericRange", Mode: "PERMUTATION", Thresholds: []interface{}{1.0}},
+		{ColumnHeader: "Setup", Type: "exact", TestValues: []interface{}{"A", "B"}},
+	}
+	if got := CalculateTotalCombinations(criteria, nil, false); got != 2 {
+		t.Errorf("CalculateTotalCombinations = %d, want 2", got)
+	}
+	if got := len(GenerateCombinations(criteria)); got != 2 {
+		t.Errorf("GenerateCombinations made %d combinations, want 2", got)
+	}
+}
+
+func TestGenerateTimeWindowsKeepsBaseWindowFirst(t *testing.T) {
+	windows := GenerateTimeWindows(60, 120, -0.5, 0.5, 30)
+	if len(windows) == 0 || windows[0]["minMinutes"] != 60 || windows[0]["maxMinutes"] != 120 {
+		t.Fatalf("first window = %v, want the base window", windows)
+	}
+	seen := make(map[[2]int]bool)
+	for _, window := range windows {
+		key := [2]int{window["minMinutes"], window["maxMinutes"]}
+		if seen[key] {
+			t.Errorf("window %v generated twice", window)
+		}
+		seen[key] = true
+		if window["maxMinutes"]-window["minMinutes"] < 15 {
+			t.Errorf("window %v is shorter than 15 minutes", window)
+		}
+	}
+	if len(windows) != 8 {
+		t.Errorf("generated %d windows, want 8", len(windows))
+	}
+}
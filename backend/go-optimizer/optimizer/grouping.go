@@ -0,0 +1,46 @@
+package optimizer
+
+import (
+	"sort"
+	"strings"
+)
+
+// processFinalResultsPerGroup ranks the combinations of every value of the group columns
+// independently, so each direction or setup reports its own best combinations. Results
+// are tagged with their direction and setup and returned group by group; combinations
+// that do not fix a group column are ranked in their own group, listed last.
+func processFinalResultsPerGroup(rawResults []Result, groupColumns []string, overlap *overlapFilter) []Result {
+	groups := make(map[string][]Result)
+	for _, result := range rawResults {
+		var values []string
+		for _, column := range groupColumns {
+			value, _ := result.Combination[column].(string)
+			values = append(values, value)
+		}
+		key := strings.Join(values, "\x00")
+		groups[key] = append(groups[key], result)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		// Groups with an unset column sort after the fixed ones.
+		iOpen, jOpen := strings.Contains("\x00"+keys[i]+"\x00", "\x00\x00"), strings.Contains("\x00"+keys[j]+"\x00", "\x00\x00")
+		if iOpen != jOpen {
+			return jOpen
+		}
+		return keys[i] < keys[j]
+	})
+
+	finalResults := []Result{}
+	for _, key := range keys {
+		for _, result := range processFinalResults(groups[key], overlap) {
+			result.Direction, _ = result.Combination[directionCriterion].(string)
+			result.Setup, _ = result.Combination[setupCriterion].(string)
+			finalResults = append(finalResults, result)
+		}
+	}
+	return finalResults
+}
@@ -0,0 +1,309 @@
+package optimizer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"go-optimizer/utils"
+	"os"
+	"strings"
+	"time"
+)
+
+// HolidayOverride adds or removes one day of an exchange's holiday calendar. Type is
+// "holiday", "halfDay" or "trading"; "trading" removes a built-in holiday or half day.
+// An empty Instrument applies to every instrument.
+type HolidayOverride struct {
+	Date       time.Time
+	Type       string
+	Instrument string
+}
+
+// instrumentExchanges maps instruments to the exchange whose calendar applies to them.
+var instrumentExchanges = map[string]string{
+	"DAX":    "XETRA",
+	"GER40":  "XETRA",
+	"DE40":   "XETRA",
+	"US30":   "NYSE",
+	"US100":  "NYSE",
+	"NAS100": "NYSE",
+	"US500":  "NYSE",
+	"SPX500": "NYSE",
+	"UK100":  "LSE",
+	"FTSE":   "LSE",
+}
+
+// LoadHolidayFile reads holiday overrides from a CSV file with a date, type and optional
+// instrument header. Dates use the same formats as the trade Date column.
+func LoadHolidayFile(path string) ([]HolidayOverride, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open holiday calendar: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read holiday calendar: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := make(map[string]int)
+	for i, name := range records[0] {
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "type"} {
+		if _, ok := header[name]; !ok {
+			return nil, fmt.Errorf("holiday calendar has no %q column", name)
+		}
+	}
+
+	var overrides []HolidayOverride
+	for i, record := range records[1:] {
+		// Rows may leave out the optional instrument column, but not the date and type.
+		if header["date"] >= len(record) || header["type"] >= len(record) {
+			return nil, fmt.Errorf("holiday calendar line %d: missing date or type", i+2)
+		}
+		date, err := utils.ParseTradeDate(record[header["date"]])
+		if err != nil {
+			return nil, fmt.Errorf("holiday calendar line %d: invalid date", i+2)
+		}
+		override := HolidayOverride{Date: date, Type: strings.TrimSpace(record[header["type"]])}
+		if override.Type != "holiday" && override.Type != "halfDay" && override.Type != "trading" {
+			return nil, fmt.Errorf("holiday calendar line %d: unknown type, expected holiday, halfDay or trading", i+2)
+		}
+		if column, ok := header["instrument"]; ok && column < len(record) {
+			override.Instrument = strings.ToUpper(strings.TrimSpace(record[column]))
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+// exchangeHolidays returns the built-in full holidays and half days of an exchange for a
+// year, keyed by date key.
+func exchangeHolidays(exchange string, year int) (holidays, halfDays map[int]bool) {
+	holidays, halfDays = make(map[int]bool), make(map[int]bool)
+	add := func(days map[int]bool, date time.Time) {
+		if !isWeekend(date) {
+			days[utils.DateKey(date)] = true
+		}
+	}
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	easter := easterSunday(year)
+
+	switch exchange {
+	case "XETRA":
+		add(holidays, day(time.January, 1))
+		add(holidays, easter.AddDate(0, 0, -2))
+		add(holidays, easter.AddDate(0, 0, 1))
+		add(holidays, day(time.May, 1))
+		add(holidays, day(time.December, 24))
+		add(holidays, day(time.December, 25))
+		add(holidays, day(time.December, 26))
+		add(holidays, day(time.December, 31))
+	case "NYSE":
+		// A New Year's Day on Saturday is not observed on the Friday before.
+		if day(time.January, 1).Weekday() != time.Saturday {
+			add(holidays, observedUS(day(time.January, 1)))
+		}
+		add(holidays, nthWeekday(year, time.January, time.Monday, 3))
+		add(holidays, nthWeekday(year, time.February, time.Monday, 3))
+		add(holidays, easter.AddDate(0, 0, -2))
+		add(holidays, nthWeekday(year, time.May, time.Monday, -1))
+		if year >= 2022 {
+			add(holidays, observedUS(day(time.June, 19)))
+		}
+		add(holidays, observedUS(day(time.July, 4)))
+		add(holidays, nthWeekday(year, time.September, time.Monday, 1))
+		thanksgiving := nthWeekday(year, time.November, time.Thursday, 4)
+		add(holidays, thanksgiving)
+		add(holidays, observedUS(day(time.December, 25)))
+
+		add(halfDays, thanksgiving.AddDate(0, 0, 1))
+		add(halfDays, day(time.December, 24))
+		if day(time.July, 4).Weekday() != time.Monday {
+			add(halfDays, day(time.July, 3))
+		}
+	case "LSE":
+		add(holidays, nextMonday(day(time.January, 1)))
+		add(holidays, easter.AddDate(0, 0, -2))
+		add(holidays, easter.AddDate(0, 0, 1))
+		add(holidays, nthWeekday(year, time.May, time.Monday, 1))
+		add(holidays, nthWeekday(year, time.May, time.Monday, -1))
+		add(holidays, nthWeekday(year, time.August, time.Monday, -1))
+		// Christmas and Boxing Day falling on a weekend are substituted by the next free weekdays.
+		christmasDays := []time.Time{day(time.December, 25), day(time.December, 26)}
+		for _, holiday := range christmasDays {
+			add(holidays, holiday)
+		}
+		substitute := day(time.December, 25)
+		for _, holiday := range christmasDays {
+			if !isWeekend(holiday) {
+				continue
+			}
+			for isWeekend(substitute) || holidays[utils.DateKey(substitute)] {
+				substitute = substitute.AddDate(0, 0, 1)
+			}
+			add(holidays, substitute)
+		}
+
+		add(halfDays, day(time.December, 24))
+		add(halfDays, day(time.December, 31))
+	}
+
+	for key := range halfDays {
+		if holidays[key] {
+			delete(halfDays, key)
+		}
+	}
+	return holidays, halfDays
+}
+
+// easterSunday computes Easter Sunday with the anonymous Gregorian algorithm.
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// nthWeekday returns the n-th given weekday of a month; n = -1 selects the last one.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	if n < 0 {
+		date := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+		for date.Weekday() != weekday {
+			date = date.AddDate(0, 0, -1)
+		}
+		return date
+	}
+	date := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	for date.Weekday() != weekday {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date.AddDate(0, 0, 7*(n-1))
+}
+
+// observedUS moves a holiday on Saturday to Friday and on Sunday to Monday.
+func observedUS(date time.Time) time.Time {
+	switch date.Weekday() {
+	case time.Saturday:
+		return date.AddDate(0, 0, -1)
+	case time.Sunday:
+		return date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// nextMonday moves a holiday on a weekend to the following Monday.
+func nextMonday(date time.Time) time.Time {
+	for isWeekend(date) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// addHolidayColumns adds Is_Holiday, Is_Half_Day, Is_Christmas_Period (24 December to
+// 1 January), Is_Holiday_Period (any of the three) and Is_Holiday_Adjacent (the weekday
+// before or after a holiday period day) from the instrument's exchange calendar and the
+// overrides. The "holidayExchange" setting selects the calendar for unmapped instruments.
+// It returns the full holidays by date key.
+func addHolidayColumns(table *TradeTable, instrument string, overrides []HolidayOverride, settings map[string]interface{}) (map[int]bool, error) {
+	if !table.HasColumn("Date_Key") {
+		return nil, fmt.Errorf("trade table has no Date column")
+	}
+	instrument = strings.ToUpper(instrument)
+	exchange, ok := settings["holidayExchange"].(string)
+	if !ok || exchange == "" {
+		exchange, ok = instrumentExchanges[instrument]
+		if !ok {
+			return nil, fmt.Errorf("no holiday calendar known for instrument %s", instrument)
+		}
+	}
+	if exchange != "XETRA" && exchange != "NYSE" && exchange != "LSE" {
+		return nil, fmt.Errorf("unknown holiday calendar %q", exchange)
+	}
+
+	holidays, halfDays := make(map[int]bool), make(map[int]bool)
+	seenYears := make(map[int]bool)
+	for _, trade := range table.Trades() {
+		year := trade.Int("Date_Key") / 10000
+		if year == 0 || seenYears[year] {
+			continue
+		}
+		seenYears[year] = true
+		yearHolidays, yearHalfDays := exchangeHolidays(exchange, year)
+		for key := range yearHolidays {
+			holidays[key] = true
+		}
+		for key := range yearHalfDays {
+			halfDays[key] = true
+		}
+	}
+	for _, override := range overrides {
+		if override.Instrument != "" && override.Instrument != instrument {
+			continue
+		}
+		key := utils.DateKey(override.Date)
+		delete(holidays, key)
+		delete(halfDays, key)
+		switch override.Type {
+		case "holiday":
+			holidays[key] = true
+		case "halfDay":
+			halfDays[key] = true
+		}
+	}
+
+	isHolidayPeriod := func(date time.Time) bool {
+		key := utils.DateKey(date)
+		return holidays[key] || halfDays[key] || isChristmasPeriod(date)
+	}
+
+	holidayColumn := table.AddColumn("Is_Holiday", BoolColumn)
+	halfDayColumn := table.AddColumn("Is_Half_Day", BoolColumn)
+	christmasColumn := table.AddColumn("Is_Christmas_Period", BoolColumn)
+	periodColumn := table.AddColumn("Is_Holiday_Period", BoolColumn)
+	adjacentColumn := table.AddColumn("Is_Holiday_Adjacent", BoolColumn)
+
+	for _, trade := range table.Trades() {
+		key := trade.Int("Date_Key")
+		if key == 0 {
+			continue
+		}
+		date := utils.DateFromKey(key)
+		holidayColumn.Bools[trade.Row] = holidays[key]
+		halfDayColumn.Bools[trade.Row] = halfDays[key]
+		christmasColumn.Bools[trade.Row] = isChristmasPeriod(date)
+		periodColumn.Bools[trade.Row] = isHolidayPeriod(date)
+		adjacentColumn.Bools[trade.Row] = !isHolidayPeriod(date) &&
+			(isHolidayPeriod(adjacentWeekday(date, -1)) || isHolidayPeriod(adjacentWeekday(date, 1)))
+	}
+	debugLog.Printf("Using the %s holiday calendar: %d holidays and %d half days.", exchange, len(holidays), len(halfDays))
+	return holidays, nil
+}
+
+func isChristmasPeriod(date time.Time) bool {
+	return (date.Month() == time.December && date.Day() >= 24) || (date.Month() == time.January && date.Day() == 1)
+}
+
+// adjacentWeekday returns the previous (step -1) or next (step 1) Monday-to-Friday day.
+func adjacentWeekday(date time.Time, step int) time.Time {
+	date = date.AddDate(0, 0, step)
+	for isWeekend(date) {
+		date = date.AddDate(0, 0, step)
+	}
+	return date
+}
@@ -0,0 +1,118 @@
+package optimizer
+
+import (
+	"go-optimizer/utils"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEasterSunday(t *testing.T) {
+	tests := []struct {
+		year  int
+		month time.Month
+		day   int
+	}{
+		{2008, time.March, 23},
+		{2019, time.April, 21},
+		{2020, time.April, 12},
+		{2021, time.April, 4},
+		{2022, time.April, 17},
+		{2023, time.April, 9},
+		{2024, time.March, 31},
+		{2025, time.April, 20},
+		{2038, time.April, 25},
+	}
+	for _, tt := range tests {
+		got := easterSunday(tt.year)
+		want := time.Date(tt.year, tt.month, tt.day, 0, 0, 0, 0, time.UTC)
+		if !got.Equal(want) {
+			t.Errorf("easterSunday(%d) = %s, want %s", tt.year, got.Format("2006-01-02"), want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestExchangeHolidays(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange string
+		year     int
+		date     string
+		holiday  bool
+		halfDay  bool
+	}{
+		{"XETRA Good Friday", "XETRA", 2024, "2024-03-29", true, false},
+		{"XETRA Easter Monday", "XETRA", 2024, "2024-04-01", true, false},
+		{"XETRA Christmas Eve", "XETRA", 2024, "2024-12-24", true, false},
+		{"XETRA Labour Day on a weekend", "XETRA", 2021, "2021-05-03", false, false},
+
+		{"NYSE Independence Day on Saturday is observed on Friday", "NYSE", 2020, "2020-07-03", true, false},
+		{"NYSE no half day on an observed holiday", "NYSE", 2020, "2020-07-02", false, false},
+		{"NYSE Independence Day on Sunday is observed on Monday", "NYSE", 2021, "2021-07-05", true, false},
+		{"NYSE July 3 is a half day", "NYSE", 2024, "2024-07-03", false, true},
+		{"NYSE Christmas on Saturday is observed on Friday", "NYSE", 2021, "2021-12-24", true, false},
+		{"NYSE New Year on Saturday is not observed", "NYSE", 2022, "2022-01-03", false, false},
+		{"NYSE Juneteenth on Sunday is observed on Monday", "NYSE", 2022, "2022-06-20", true, false},
+		{"NYSE no Juneteenth before 2022", "NYSE", 2021, "2021-06-18", false, false},
+		{"NYSE Good Friday", "NYSE", 2025, "2025-04-18", true, false},
+		{"NYSE Thanksgiving", "NYSE", 2024, "2024-11-28", true, false},
+		{"NYSE day after Thanksgiving", "NYSE", 2024, "2024-11-29", false, true},
+
+		{"LSE New Year on Saturday moves to Monday", "LSE", 2022, "2022-01-03", true, false},
+		{"LSE New Year on Sunday moves to Monday", "LSE", 2023, "2023-01-02", true, false},
+		{"LSE Christmas substitute for Saturday", "LSE", 2021, "2021-12-27", true, false},
+		{"LSE Boxing Day substitute for Sunday", "LSE", 2021, "2021-12-28", true, false},
+		{"LSE Christmas on Sunday after Boxing Day on Monday", "LSE", 2022, "2022-12-27", true, false},
+		{"LSE Boxing Day on Saturday moves past Christmas", "LSE", 2020, "2020-12-28", true, false},
+		{"LSE Easter Monday", "LSE", 2023, "2023-04-10", true, false},
+		{"LSE New Year's Eve is a half day", "LSE", 2024, "2024-12-31", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, err := time.Parse("2006-01-02", tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			holidays, halfDays := exchangeHolidays(tt.exchange, tt.year)
+			key := utils.DateKey(date)
+			if holidays[key] != tt.holiday {
+				t.Errorf("holiday = %v, want %v", holidays[key], tt.holiday)
+			}
+			if halfDays[key] != tt.halfDay {
+				t.Errorf("half day = %v, want %v", halfDays[key], tt.halfDay)
+			}
+		})
+	}
+}
+
+func TestLoadHolidayFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		overrides int
+		wantErr   bool
+	}{
+		{"with instrument", "date,type,instrument\n2024-12-24,trading,DAX\n2024-05-09,holiday\n", 2, false},
+		{"short row", "date,type,instrument\n2024-12-24\n", 0, true},
+		{"type column last", "instrument,date,type\nDAX,2024-12-24\n", 0, true},
+		{"unknown type", "date,type\n2024-12-24,closed\n", 0, true},
+		{"invalid date", "date,type\nsoon,holiday\n", 0, true},
+		{"missing header", "day,type\n2024-12-24,holiday\n", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "holidays.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			overrides, err := LoadHolidayFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(overrides) != tt.overrides {
+				t.Errorf("got %d overrides, want %d", len(overrides), tt.overrides)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package optimizer
+
+import (
+	"fmt"
+	"go-optimizer/utils"
+	"time"
+)
+
+// marketTimeColumns maps the derived minutes-of-day columns to their market.
+var marketTimeColumns = map[string]string{
+	"Time_Frankfurt": "Frankfurt",
+	"Time_London":    "London",
+	"Time_NewYork":   "NewYork",
+}
+
+// timeFilterColumn holds the minutes of day the TimeFilter and the predefined timeRange
+// filter compare against, in the zone chosen by the "timeFilterZone" setting.
+const timeFilterColumn = "TimeFilter_Minutes"
+
+// addMarketTimeColumns converts each trade's Date and Time from broker server time to UTC
+// using the "brokerTime" setting and adds Timestamp_UTC (unix seconds), the exchange-local
+// minutes of day per market and TimeFilter_Minutes. Exchange-local times come from the IANA
+// database, so windows expressed in market time stay aligned across DST transitions.
+func addMarketTimeColumns(table *TradeTable, settings map[string]interface{}) error {
+	if !table.HasColumn("Date_Key") || !table.HasColumn("Time") {
+		return fmt.Errorf("trade table has no Date or Time column")
+	}
+
+	brokerSetting, _ := settings["brokerTime"].(map[string]interface{})
+	clock, err := utils.NewBrokerClock(brokerSetting)
+	if err != nil {
+		return err
+	}
+	if clock.Model == "euOffsColumn" && !table.HasColumn("EU_OFFS") {
+		return fmt.Errorf("broker time model 'euOffsColumn' needs the EU_OFFS column")
+	}
+
+	zones := make(map[string]*time.Location)
+	for column, market := range marketTimeColumns {
+		zone, err := time.LoadLocation(utils.MarketZones[market])
+		if err != nil {
+			return err
+		}
+		zones[column] = zone
+	}
+
+	// An empty zone keeps the TimeFilter in broker server time, as before.
+	var filterZone *time.Location
+	if market, _ := settings["timeFilterZone"].(string); market != "" {
+		zoneName, ok := utils.MarketZones[market]
+		if !ok {
+			return fmt.Errorf("unknown timeFilterZone %q", market)
+		}
+		filterZone, _ = time.LoadLocation(zoneName)
+		debugLog.Printf("Time filters are evaluated in %s time.", market)
+	}
+
+	timestamps := table.AddColumn("Timestamp_UTC", IntColumn)
+	filterMinutes := table.AddColumn(timeFilterColumn, IntColumn)
+	marketMinutes := make(map[string]*TradeColumn)
+	for column := range marketTimeColumns {
+		marketMinutes[column] = table.AddColumn(column, IntColumn)
+	}
+
+	invalidTimes := 0
+	for _, trade := range table.Trades() {
+		dateKey := trade.Int("Date_Key")
+		serverMinutes, err := utils.TimeToMinutes(trade.String("Time"))
+		if dateKey == 0 || err != nil {
+			filterMinutes.Ints[trade.Row] = -1
+			invalidTimes++
+			continue
+		}
+
+		wall := utils.DateFromKey(dateKey).Add(time.Duration(serverMinutes) * time.Minute)
+		utc := clock.ToUTC(wall, trade.Int("EU_OFFS"))
+
+		timestamps.Ints[trade.Row] = int(utc.Unix())
+		for column, zone := range zones {
+			marketMinutes[column].Ints[trade.Row] = utils.MinutesOfDay(utc, zone)
+		}
+		if filterZone != nil {
+			filterMinutes.Ints[trade.Row] = utils.MinutesOfDay(utc, filterZone)
+		} else {
+			filterMinutes.Ints[trade.Row] = serverMinutes
+		}
+	}
+
+	if invalidTimes > 0 {
+		debugLog.Printf("WARNING: %d trades have no valid Date/Time; their market time columns are empty.", invalidTimes)
+	}
+	return nil
+}
+
+// tradeFilterMinutes returns the minutes of day a time filter compares against: the
+// derived TimeFilter_Minutes if available, the raw server Time otherwise.
+func tradeFilterMinutes(trade Trade) (int, error) {
+	if col := trade.Column(timeFilterColumn); col != nil {
+		if col.Ints[trade.Row] < 0 {
+			return 0, fmt.Errorf("trade %d has no valid Time", trade.ID())
+		}
+		return col.Ints[trade.Row], nil
+	}
+	return utils.TimeToMinutes(trade.String("Time"))
+}
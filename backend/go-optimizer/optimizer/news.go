@@ -0,0 +1,203 @@
+package optimizer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewsEvent is one entry of the economic calendar.
+type NewsEvent struct {
+	Time     time.Time
+	Currency string
+	Impact   string
+}
+
+// noNewsMinutes is stored when no matching event lies within a week of the trade, so
+// trades outside the calendar's coverage pass every "no news within X minutes" range.
+const noNewsMinutes = 7 * 24 * 60
+
+// newsTimeLayouts are the accepted timestamp formats of a calendar file; times without
+// a zone are UTC.
+var newsTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05"}
+
+// instrumentCurrencies maps index instruments to the currencies whose news move them.
+// Six-letter forex pairs are split into their two currencies.
+var instrumentCurrencies = map[string][]string{
+	"DAX":    {"EUR"},
+	"GER40":  {"EUR"},
+	"DE40":   {"EUR"},
+	"US30":   {"USD"},
+	"US100":  {"USD"},
+	"NAS100": {"USD"},
+	"US500":  {"USD"},
+	"SPX500": {"USD"},
+	"UK100":  {"GBP"},
+	"FTSE":   {"GBP"},
+}
+
+// LoadNewsCalendarFile reads a calendar from a CSV file with a timestamp, currency and
+// impact header, or from a JSON array of objects with the same keys.
+func LoadNewsCalendarFile(path string) ([]NewsEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open news calendar: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var raw []map[string]string
+		if err := json.NewDecoder(file).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("could not decode news calendar: not a JSON array of events")
+		}
+		var events []NewsEvent
+		for i, entry := range raw {
+			event, err := parseNewsEvent(entry["timestamp"], entry["currency"], entry["impact"])
+			if err != nil {
+				return nil, fmt.Errorf("news calendar entry %d: %w", i, err)
+			}
+			events = append(events, event)
+		}
+		return events, nil
+	}
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not read news calendar: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := make(map[string]int)
+	for i, name := range records[0] {
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"timestamp", "currency", "impact"} {
+		if _, ok := header[name]; !ok {
+			return nil, fmt.Errorf("news calendar has no %q column", name)
+		}
+	}
+
+	var events []NewsEvent
+	for i, record := range records[1:] {
+		event, err := parseNewsEvent(record[header["timestamp"]], record[header["currency"]], record[header["impact"]])
+		if err != nil {
+			return nil, fmt.Errorf("news calendar line %d: %w", i+2, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func parseNewsEvent(timestamp, currency, impact string) (NewsEvent, error) {
+	for _, layout := range newsTimeLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(timestamp)); err == nil {
+			return NewsEvent{
+				Time:     t.UTC(),
+				Currency: strings.ToUpper(strings.TrimSpace(currency)),
+				Impact:   strings.ToLower(strings.TrimSpace(impact)),
+			}, nil
+		}
+	}
+	return NewsEvent{}, fmt.Errorf("invalid timestamp")
+}
+
+// newsCurrencies returns the currencies whose events apply to the instrument. The
+// "newsCurrencies" setting overrides the built-in mapping.
+func newsCurrencies(instrument string, settings map[string]interface{}) []string {
+	if configured, ok := settings["newsCurrencies"].([]interface{}); ok {
+		var currencies []string
+		for _, c := range configured {
+			if s, ok := c.(string); ok {
+				currencies = append(currencies, strings.ToUpper(s))
+			}
+		}
+		return currencies
+	}
+	instrument = strings.ToUpper(instrument)
+	if currencies, ok := instrumentCurrencies[instrument]; ok {
+		return currencies
+	}
+	if len(instrument) == 6 {
+		return []string{instrument[:3], instrument[3:]}
+	}
+	return nil
+}
+
+// addNewsColumns adds Minutes_To_Next_News, Minutes_Since_Last_News and
+// Minutes_To_Nearest_News, measured from the trade's UTC time to the events of the
+// instrument's currencies whose impact is listed in the "newsImpact" setting (default
+// "high"). Distances are capped at noNewsMinutes.
+func addNewsColumns(table *TradeTable, events []NewsEvent, instrument string, settings map[string]interface{}) error {
+	if !table.HasColumn("Timestamp_UTC") {
+		return fmt.Errorf("trade table has no market time columns")
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no news calendar loaded")
+	}
+	currencies := newsCurrencies(instrument, settings)
+	if len(currencies) == 0 {
+		return fmt.Errorf("no news currencies known for instrument %s", instrument)
+	}
+
+	impacts := map[string]bool{"high": true}
+	if configured, ok := settings["newsImpact"].([]interface{}); ok {
+		impacts = make(map[string]bool)
+		for _, impact := range configured {
+			if s, ok := impact.(string); ok {
+				impacts[strings.ToLower(s)] = true
+			}
+		}
+	}
+
+	var eventTimes []int64
+	for _, event := range events {
+		if !impacts[event.Impact] {
+			continue
+		}
+		for _, currency := range currencies {
+			if event.Currency == currency {
+				eventTimes = append(eventTimes, event.Time.Unix())
+				break
+			}
+		}
+	}
+	sort.Slice(eventTimes, func(i, j int) bool { return eventTimes[i] < eventTimes[j] })
+	debugLog.Printf("Using %d news events for %s (%s).", len(eventTimes), instrument, strings.Join(currencies, ", "))
+
+	toNext := table.AddColumn("Minutes_To_Next_News", FloatColumn)
+	sinceLast := table.AddColumn("Minutes_Since_Last_News", FloatColumn)
+	toNearest := table.AddColumn("Minutes_To_Nearest_News", FloatColumn)
+
+	for _, trade := range table.Trades() {
+		// Trades without a valid time keep 0, so they never pass a news distance range.
+		if trade.Int(timeFilterColumn) < 0 {
+			continue
+		}
+		tradeTime := int64(trade.Int("Timestamp_UTC"))
+		next := sort.Search(len(eventTimes), func(i int) bool { return eventTimes[i] >= tradeTime })
+
+		nextMinutes, lastMinutes := float64(noNewsMinutes), float64(noNewsMinutes)
+		if next < len(eventTimes) {
+			nextMinutes = capNewsMinutes(eventTimes[next] - tradeTime)
+		}
+		if next > 0 {
+			lastMinutes = capNewsMinutes(tradeTime - eventTimes[next-1])
+		}
+
+		toNext.Floats[trade.Row] = nextMinutes
+		sinceLast.Floats[trade.Row] = lastMinutes
+		toNearest.Floats[trade.Row] = math.Min(nextMinutes, lastMinutes)
+	}
+	return nil
+}
+
+func capNewsMinutes(seconds int64) float64 {
+	return math.Min(float64(seconds)/60, noNewsMinutes)
+}
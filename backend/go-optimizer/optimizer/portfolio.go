@@ -0,0 +1,366 @@
+package optimizer
+
+import (
+	"fmt"
+	"go-optimizer/utils"
+	"math"
+	"sort"
+	"time"
+)
+
+// maxExhaustiveSubsets bounds the exhaustive portfolio search; larger searches fall back to greedy.
+const maxExhaustiveSubsets = 200000
+
+// PortfolioMember is one final result traded with its best strategy.
+type PortfolioMember struct {
+	ResultIndex int         `json:"resultIndex"`
+	Combination Combination `json:"combination"`
+	Instrument  string      `json:"instrument,omitempty"`
+	Timeframe   string      `json:"timeframe,omitempty"`
+	Strategy    string      `json:"strategy"`
+	TradeCount  int         `json:"tradeCount"`
+	NetProfit   float64     `json:"netProfit"`
+}
+
+// Portfolio is the selected subset of final results and its combined performance.
+type Portfolio struct {
+	Instrument  string            `json:"instrument,omitempty"`
+	Search      string            `json:"search"`
+	Objective   string            `json:"objective"`
+	Score       float64           `json:"score"`
+	Members     []PortfolioMember `json:"members"`
+	TradeCount  int               `json:"tradeCount"`
+	NetProfit   float64           `json:"netProfit"`
+	MaxDrawdown float64           `json:"maxDrawdown"`
+	// Correlation is the Pearson correlation of daily P&L between the members, in member order.
+	Correlation [][]float64 `json:"correlation"`
+}
+
+// portfolioSettings is the "portfolio" setting with its defaults applied.
+type portfolioSettings struct {
+	size              int
+	maxCorrelation    float64
+	objective         string
+	search            string
+	maxCandidates     int
+	acrossInstruments bool
+	sizing            positionSizing
+}
+
+// portfolioCandidate is a final result with its trade stream under its best strategy.
+type portfolioCandidate struct {
+	member PortfolioMember
+	trades []portfolioTrade
+	daily  map[int]float64
+}
+
+// portfolioTrade is a member's trade; its outcome is sized again on the portfolio's shared
+// account under a compounding sizing model.
+type portfolioTrade struct {
+	timestamp int64
+	outcome   tradeOutcome
+}
+
+func newPortfolioSettings(settings map[string]interface{}) (portfolioSettings, bool) {
+	setting, _ := settings["portfolio"].(map[string]interface{})
+	if enabled, _ := setting["enabled"].(bool); !enabled {
+		return portfolioSettings{}, false
+	}
+
+	ps := portfolioSettings{size: 3, maxCorrelation: 0.7, objective: "returnToDrawdown", search: "greedy", maxCandidates: 30, acrossInstruments: true, sizing: newPositionSizing(settings)}
+	if size, ok := setting["size"].(float64); ok && size >= 1 {
+		ps.size = int(size)
+	}
+	if maxCorrelation, ok := setting["maxCorrelation"].(float64); ok {
+		ps.maxCorrelation = maxCorrelation
+	}
+	if objective, ok := setting["objective"].(string); ok && objective != "" {
+		ps.objective = objective
+	}
+	if search, ok := setting["search"].(string); ok && search != "" {
+		ps.search = search
+	}
+	if maxCandidates, ok := setting["maxCandidates"].(float64); ok && maxCandidates >= 1 {
+		ps.maxCandidates = int(maxCandidates)
+	}
+	if across, ok := setting["acrossInstruments"].(bool); ok {
+		ps.acrossInstruments = across
+	}
+	return ps, true
+}
+
+// BuildPortfolios runs the portfolio stage configured by the "portfolio" setting. The
+// finalists, in ranking order, are traded with their best strategy; a subset of "size"
+// members whose pairwise daily P&L correlation stays within "maxCorrelation" is selected
+// by the "greedy" or "exhaustive" search to maximize the "objective" ("netProfit" or
+// "returnToDrawdown"). One portfolio is built across all instruments, or one per
+// instrument with "acrossInstruments": false.
+func BuildPortfolios(results []Result, datasets []Dataset, settings map[string]interface{}) ([]Portfolio, error) {
+	ps, enabled := newPortfolioSettings(settings)
+	if !enabled {
+		return nil, nil
+	}
+	if ps.objective != "netProfit" && ps.objective != "returnToDrawdown" {
+		return nil, fmt.Errorf("unknown portfolio objective %q", ps.objective)
+	}
+	if ps.search != "greedy" && ps.search != "exhaustive" {
+		return nil, fmt.Errorf("unknown portfolio search %q", ps.search)
+	}
+
+	byInstrument := make(map[string][]portfolioCandidate)
+	var instruments []string
+	for i, result := range results {
+		trades := resultTrades(result, datasets)
+		if trades == nil {
+			continue
+		}
+		key := ""
+		if !ps.acrossInstruments {
+			key = result.Instrument
+		}
+		if len(byInstrument[key]) >= ps.maxCandidates {
+			continue
+		}
+		candidate, ok := newPortfolioCandidate(i, result, trades, settings)
+		if !ok {
+			continue
+		}
+		if _, seen := byInstrument[key]; !seen {
+			instruments = append(instruments, key)
+		}
+		byInstrument[key] = append(byInstrument[key], candidate)
+	}
+
+	var portfolios []Portfolio
+	for _, instrument := range instruments {
+		portfolio := selectPortfolio(byInstrument[instrument], ps)
+		portfolio.Instrument = instrument
+		debugLog.Printf("Portfolio %s: %d members, net profit %.2f, max drawdown %.2f.", instrument, len(portfolio.Members), portfolio.NetProfit, portfolio.MaxDrawdown)
+		portfolios = append(portfolios, portfolio)
+	}
+	return portfolios, nil
+}
+
+// newPortfolioCandidate re-applies the result's combination and collects the P&L of its
+// trades under the result's best strategy.
+func newPortfolioCandidate(index int, result Result, trades []Trade, settings map[string]interface{}) (portfolioCandidate, bool) {
+	strategyName := bestStrategy(result.StrategyScores)
+	strategy, ok := findStrategy(strategyName)
+	if !ok {
+		return portfolioCandidate{}, false
+	}
+
+	filteredTrades, ltaCombination, candleSizeTpRatio := ApplyFilters(trades, result.Combination)
+
+	candidate := portfolioCandidate{
+		member: PortfolioMember{
+			ResultIndex: index,
+			Combination: result.Combination,
+			Instrument:  result.Instrument,
+			Timeframe:   result.Timeframe,
+			Strategy:    strategyName,
+		},
+		daily: make(map[int]float64),
+	}
+	for _, outcome := range strategyOutcomes(filteredTrades, ltaCombination, candleSizeTpRatio, strategy, settings) {
+		candidate.trades = append(candidate.trades, portfolioTrade{timestamp: tradeTimestamp(outcome.trade), outcome: outcome})
+		candidate.daily[outcome.trade.Int("Date_Key")] += outcome.pnl
+		candidate.member.NetProfit += outcome.pnl
+	}
+	candidate.member.TradeCount = len(candidate.trades)
+	return candidate, len(candidate.trades) > 0
+}
+
+// tradeTimestamp orders trades chronologically: the UTC time if derived, else the
+// broker date and time.
+func tradeTimestamp(trade Trade) int64 {
+	if timestamp := trade.Int("Timestamp_UTC"); timestamp > 0 {
+		return int64(timestamp)
+	}
+	minutes, _ := utils.TimeToMinutes(trade.String("Time"))
+	return utils.DateFromKey(trade.Int("Date_Key")).Add(time.Duration(minutes) * time.Minute).Unix()
+}
+
+// selectPortfolio searches the members maximizing the objective under the correlation limit.
+func selectPortfolio(candidates []portfolioCandidate, ps portfolioSettings) Portfolio {
+	n := len(candidates)
+	correlation := make([][]float64, n)
+	for i := range correlation {
+		correlation[i] = make([]float64, n)
+		for j := range correlation[i] {
+			if i == j {
+				correlation[i][j] = 1
+			} else if j < i {
+				correlation[i][j] = correlation[j][i]
+			} else {
+				correlation[i][j] = dailyCorrelation(candidates[i].daily, candidates[j].daily)
+			}
+		}
+	}
+	compatible := func(members []int, candidate int) bool {
+		for _, member := range members {
+			if correlation[member][candidate] > ps.maxCorrelation {
+				return false
+			}
+		}
+		return true
+	}
+	score := func(members []int) float64 {
+		netProfit, maxDrawdown := combinedEquity(candidates, members, &ps.sizing)
+		if ps.objective == "netProfit" {
+			return netProfit
+		}
+		// One lost trade is the smallest drawdown considered, so a short lucky streak
+		// does not divide by zero.
+		return netProfit / math.Max(maxDrawdown, 100)
+	}
+
+	size := ps.size
+	if size > n {
+		size = n
+	}
+	search := ps.search
+	if search == "exhaustive" && binomial(n, size) > maxExhaustiveSubsets {
+		debugLog.Printf("WARNING: Exhaustive portfolio search over %d candidates is too large, using greedy.", n)
+		search = "greedy"
+	}
+
+	var best []int
+	bestScore := math.Inf(-1)
+	if search == "exhaustive" {
+		// Smaller subsets are considered too, the correlation limit may rule out size members.
+		var walk func(start int, members []int)
+		walk = func(start int, members []int) {
+			if len(members) > 0 {
+				if s := score(members); s > bestScore {
+					best, bestScore = append([]int(nil), members...), s
+				}
+			}
+			if len(members) == size {
+				return
+			}
+			for i := start; i < n; i++ {
+				if compatible(members, i) {
+					walk(i+1, append(members, i))
+				}
+			}
+		}
+		walk(0, nil)
+	} else {
+		for len(best) < size {
+			next, nextScore := -1, math.Inf(-1)
+			for i := 0; i < n; i++ {
+				if containsInt(best, i) || !compatible(best, i) {
+					continue
+				}
+				if s := score(append(append([]int(nil), best...), i)); s > nextScore {
+					next, nextScore = i, s
+				}
+			}
+			if next < 0 {
+				break
+			}
+			best, bestScore = append(best, next), nextScore
+		}
+	}
+
+	portfolio := Portfolio{Search: search, Objective: ps.objective, Score: bestScore, Members: []PortfolioMember{}, Correlation: [][]float64{}}
+	if len(best) == 0 {
+		portfolio.Score = 0
+		return portfolio
+	}
+	portfolio.NetProfit, portfolio.MaxDrawdown = combinedEquity(candidates, best, &ps.sizing)
+	for _, i := range best {
+		portfolio.Members = append(portfolio.Members, candidates[i].member)
+		portfolio.TradeCount += candidates[i].member.TradeCount
+		row := make([]float64, 0, len(best))
+		for _, j := range best {
+			row = append(row, math.Round(correlation[i][j]*1000)/1000)
+		}
+		portfolio.Correlation = append(portfolio.Correlation, row)
+	}
+	return portfolio
+}
+
+// combinedEquity merges the members' trades chronologically and returns the net profit and
+// the maximum drawdown of the combined equity curve. Under a compounding sizing model the
+// members trade one shared account, so the merged trades are sized again in order instead
+// of adding up independent accounts.
+func combinedEquity(candidates []portfolioCandidate, members []int, sizing *positionSizing) (float64, float64) {
+	var trades []portfolioTrade
+	for _, i := range members {
+		trades = append(trades, candidates[i].trades...)
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].timestamp < trades[j].timestamp })
+
+	account := sizing.newAccount()
+	equity, peak, maxDrawdown := 0.0, 0.0, 0.0
+	for _, trade := range trades {
+		pnl := trade.outcome.pnl
+		if sizing.compounds() {
+			if account.blown() {
+				break
+			}
+			pnl = account.settle(trade.outcome.trade, trade.outcome.pips, trade.outcome.slPips)
+		}
+		equity += pnl
+		peak = math.Max(peak, equity)
+		maxDrawdown = math.Max(maxDrawdown, peak-equity)
+	}
+	return equity, maxDrawdown
+}
+
+// dailyCorrelation is the Pearson correlation of two daily P&L series over the days
+// either traded; a day without trades counts as 0.
+func dailyCorrelation(a, b map[int]float64) float64 {
+	days := make(map[int]bool)
+	for day := range a {
+		days[day] = true
+	}
+	for day := range b {
+		days[day] = true
+	}
+	n := float64(len(days))
+	if n < 2 {
+		return 0
+	}
+
+	var sumA, sumB float64
+	for day := range days {
+		sumA += a[day]
+		sumB += b[day]
+	}
+	meanA, meanB := sumA/n, sumB/n
+
+	var cov, varA, varB float64
+	for day := range days {
+		da, db := a[day]-meanA, b[day]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
+
+func binomial(n, k int) int {
+	result := 1
+	for i := 1; i <= k; i++ {
+		result = result * (n - k + i) / i
+		if result > maxExhaustiveSubsets {
+			return result
+		}
+	}
+	return result
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package optimizer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCombinedEquitySharesCompoundingAccount(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"instrument", StringColumn}}, []interface{}{"DAX"}, []interface{}{"DAX"})
+	candidate := func(timestamp int64, trade Trade, pips float64, pnl float64) portfolioCandidate {
+		return portfolioCandidate{trades: []portfolioTrade{{timestamp: timestamp, outcome: tradeOutcome{trade: trade, pips: pips, slPips: 10, pnl: pnl}}}}
+	}
+	// Each member made +1R on its own 10000 account.
+	candidates := []portfolioCandidate{candidate(1, trades[0], 10, 100), candidate(2, trades[1], 10, 100)}
+
+	tests := []struct {
+		name      string
+		setting   map[string]interface{}
+		netProfit float64
+	}{
+		{"fixed risk adds the members", nil, 200},
+		{"percent risk compounds one account", map[string]interface{}{"model": "percentRisk"}, 201},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sizing := newPositionSizing(map[string]interface{}{"positionSizing": tt.setting})
+			netProfit, _ := combinedEquity(candidates, []int{0, 1}, &sizing)
+			if math.Abs(netProfit-tt.netProfit) > 1e-9 {
+				t.Errorf("net profit = %g, want %g", netProfit, tt.netProfit)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package optimizer
+
+import (
+	"fmt"
+	"go-optimizer/utils"
+	"time"
+)
+
+// sessionCriterion is the virtual criteria key whose exact values are session names.
+// A trade matches a session if its derived Session_<name> column is true, so overlapping
+// sessions such as US_PreOpen and London_NY_Overlap can both match the same trade.
+const sessionCriterion = "Session"
+
+const sessionColumnPrefix = "Session_"
+
+// ValidateTradingSessions checks a session catalog and fills in optional keys.
+func ValidateTradingSessions(sessions []map[string]interface{}) ([]map[string]interface{}, error) {
+	seenNames := make(map[string]bool)
+	for i, session := range sessions {
+		name, ok := session["name"].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("session %d has no name", i)
+		}
+		if seenNames[name] {
+			return nil, fmt.Errorf("session %q is defined twice", name)
+		}
+		seenNames[name] = true
+
+		// LoadMarketZone resolves an empty name to UTC, so a missing zone would go unnoticed.
+		zoneName, _ := session["zone"].(string)
+		if zoneName == "" {
+			return nil, fmt.Errorf("session %q has no zone", name)
+		}
+		if _, err := utils.LoadMarketZone(zoneName); err != nil {
+			return nil, fmt.Errorf("session %q: %w", name, err)
+		}
+		for _, key := range []string{"start", "end"} {
+			value, _ := session[key].(string)
+			if _, err := utils.TimeToMinutes(value); err != nil {
+				return nil, fmt.Errorf("session %q has an invalid %s time %q", name, key, value)
+			}
+		}
+		if _, ok := session["dstAware"].(bool); !ok {
+			session["dstAware"] = true
+		}
+	}
+	return sessions, nil
+}
+
+// sessionTestValues returns every session name plus the "any" choice.
+func sessionTestValues() []interface{} {
+	var values []interface{}
+	for _, session := range TradingSessions {
+		values = append(values, session["name"])
+	}
+	return append(values, nil)
+}
+
+// addSessionColumns adds a Session_<name> flag per trading session, evaluated against the
+// DST-corrected trade time from addMarketTimeColumns. A window whose end is before its
+// start runs over midnight.
+func addSessionColumns(table *TradeTable) error {
+	if !table.HasColumn("Timestamp_UTC") {
+		return fmt.Errorf("trade table has no market time columns")
+	}
+
+	for _, session := range TradingSessions {
+		zone, err := utils.LoadMarketZone(session["zone"].(string))
+		if err != nil {
+			return err
+		}
+		start, _ := utils.TimeToMinutes(session["start"].(string))
+		end, _ := utils.TimeToMinutes(session["end"].(string))
+		dstAware := session["dstAware"].(bool)
+
+		flags := table.AddColumn(sessionColumnPrefix+session["name"].(string), BoolColumn)
+		for _, trade := range table.Trades() {
+			if trade.Int(timeFilterColumn) < 0 {
+				continue
+			}
+			instant := time.Unix(int64(trade.Int("Timestamp_UTC")), 0).UTC()
+			var minutes int
+			if dstAware {
+				minutes = utils.MinutesOfDay(instant, zone)
+			} else {
+				minutes = utils.StandardMinutesOfDay(instant, zone)
+			}
+			if start <= end {
+				flags.Bools[trade.Row] = minutes >= start && minutes < end
+			} else {
+				flags.Bools[trade.Row] = minutes >= start || minutes < end
+			}
+		}
+	}
+	return nil
+}
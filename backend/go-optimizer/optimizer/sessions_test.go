@@ -0,0 +1,62 @@
+package optimizer
+
+import "testing"
+
+func TestValidateTradingSessions(t *testing.T) {
+	session := func(extra map[string]interface{}) map[string]interface{} {
+		s := map[string]interface{}{"name": "Open", "zone": "Frankfurt", "start": "09:00", "end": "10:00"}
+		for key, value := range extra {
+			s[key] = value
+		}
+		return s
+	}
+	tests := []struct {
+		name    string
+		session map[string]interface{}
+		wantErr bool
+	}{
+		{"market zone", session(nil), false},
+		{"IANA zone", session(map[string]interface{}{"zone": "America/Chicago"}), false},
+		{"explicit UTC", session(map[string]interface{}{"zone": "UTC"}), false},
+		{"missing zone", session(map[string]interface{}{"zone": nil}), true},
+		{"empty zone", session(map[string]interface{}{"zone": ""}), true},
+		{"unknown zone", session(map[string]interface{}{"zone": "Atlantis"}), true},
+		{"invalid time", session(map[string]interface{}{"end": "ten"}), true},
+		{"no name", session(map[string]interface{}{"name": ""}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions, err := ValidateTradingSessions([]map[string]interface{}{tt.session})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && sessions[0]["dstAware"] != true {
+				t.Errorf("dstAware = %v, want the default true", sessions[0]["dstAware"])
+			}
+		})
+	}
+}
+
+func TestApplyFiltersSession(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"Session_London_Open", BoolColumn}, {"Session_US_Cash_Open", BoolColumn}},
+		[]interface{}{true, false}, []interface{}{false, true}, []interface{}{true, true},
+	)
+	tests := []struct {
+		session interface{}
+		want    int
+	}{
+		{"London_Open", 2},
+		{"US_Cash_Open", 2},
+		{"Asia_Open", 0},
+		{nil, 3},
+	}
+	for _, tt := range tests {
+		combo := Combination{}
+		if tt.session != nil {
+			combo[sessionCriterion] = tt.session
+		}
+		if filtered, _, _ := ApplyFilters(trades, combo); len(filtered) != tt.want {
+			t.Errorf("session %v kept %d trades, want %d", tt.session, len(filtered), tt.want)
+		}
+	}
+}
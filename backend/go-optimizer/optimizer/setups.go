@@ -0,0 +1,56 @@
+package optimizer
+
+import "sort"
+
+// setupCriterion is the trade column holding the setup name.
+const setupCriterion = "Setup"
+
+// setupApplies reports whether a strategy with the given setups and excludedSetups is
+// scored on a trade of the given setup. An empty setups list accepts every setup.
+func setupApplies(setup string, setups, excludedSetups []string) bool {
+	for _, excluded := range excludedSetups {
+		if setup == excluded {
+			return false
+		}
+	}
+	if len(setups) == 0 {
+		return true
+	}
+	for _, included := range setups {
+		if setup == included {
+			return true
+		}
+	}
+	return false
+}
+
+// tradeSetups returns the distinct setups of the trades in sorted order.
+func tradeSetups(trades []Trade) []string {
+	seen := make(map[string]bool)
+	var setups []string
+	for _, trade := range trades {
+		if setup := trade.String(setupCriterion); !seen[setup] {
+			seen[setup] = true
+			setups = append(setups, setup)
+		}
+	}
+	sort.Strings(setups)
+	return setups
+}
+
+// SplitSetupCriteria makes every combination setup-specific for the "splitSetups" setting:
+// the Setup criterion tests each setup present in the trades but no "any".
+func SplitSetupCriteria(criteria []CombinationCriterion, trades []Trade) []CombinationCriterion {
+	split := CombinationCriterion{ColumnHeader: setupCriterion, Type: "exact", TestValues: []interface{}{}, Thresholds: []interface{}{}}
+	for _, setup := range tradeSetups(trades) {
+		split.TestValues = append(split.TestValues, setup)
+	}
+
+	result := []CombinationCriterion{split}
+	for _, criterion := range criteria {
+		if criterion.ColumnHeader != setupCriterion {
+			result = append(result, criterion)
+		}
+	}
+	return result
+}
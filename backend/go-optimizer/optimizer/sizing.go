@@ -0,0 +1,151 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// positionSizing is the "positionSizing" setting with its defaults applied. The models are
+//   - "fixedRisk": every trade risks riskAmount; the default, risking 100 as before.
+//   - "percentRisk": every trade risks riskPercent of the compounding account.
+//   - "fixedLot": every trade is lotSize lots, worth the instrument's pipValues entry per
+//     pip and lot (1 if missing).
+//   - "kelly": every trade risks kellyFraction of the Kelly fraction estimated from the
+//     strategy's earlier trades, capped at maxRiskPercent of the compounding account;
+//     riskPercent is used until kellyWarmup trades are known.
+//
+// The account starts at startingBalance. Under the compounding models a blown account,
+// one whose balance fell to 0 or below, takes no further trades; the fixed models size
+// every trade alike and ignore the balance.
+type positionSizing struct {
+	model           string
+	riskAmount      float64
+	riskPercent     float64
+	startingBalance float64
+	lotSize         float64
+	pipValues       map[string]interface{}
+	kellyFraction   float64
+	maxRiskPercent  float64
+	kellyWarmup     int
+}
+
+var positionSizingModels = []string{"fixedRisk", "percentRisk", "fixedLot", "kelly"}
+
+func newPositionSizing(settings map[string]interface{}) positionSizing {
+	ps := positionSizing{model: "fixedRisk", riskAmount: 100, riskPercent: 1, startingBalance: 10000, lotSize: 1, kellyFraction: 0.5, maxRiskPercent: 2, kellyWarmup: 20}
+	setting, _ := settings["positionSizing"].(map[string]interface{})
+	if model, ok := setting["model"].(string); ok && model != "" {
+		ps.model = model
+	}
+	number := func(key string, target *float64) {
+		if value, ok := setting[key].(float64); ok && value > 0 {
+			*target = value
+		}
+	}
+	number("riskAmount", &ps.riskAmount)
+	number("riskPercent", &ps.riskPercent)
+	number("startingBalance", &ps.startingBalance)
+	number("lotSize", &ps.lotSize)
+	number("kellyFraction", &ps.kellyFraction)
+	number("maxRiskPercent", &ps.maxRiskPercent)
+	if warmup, ok := setting["kellyWarmup"].(float64); ok && warmup >= 0 {
+		ps.kellyWarmup = int(warmup)
+	}
+	ps.pipValues, _ = setting["pipValues"].(map[string]interface{})
+	return ps
+}
+
+// ValidatePositionSizing checks the "positionSizing" setting.
+func ValidatePositionSizing(settings map[string]interface{}) error {
+	if _, ok := settings["positionSizing"]; !ok {
+		return nil
+	}
+	if _, ok := settings["positionSizing"].(map[string]interface{}); !ok {
+		return fmt.Errorf("'positionSizing' setting is not an object")
+	}
+	ps := newPositionSizing(settings)
+	for _, model := range positionSizingModels {
+		if ps.model == model {
+			debugLog.Printf("Position sizing model: %s.", ps.model)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown position sizing model %q, expected one of %s", ps.model, strings.Join(positionSizingModels, ", "))
+}
+
+// compounds reports whether the trade results depend on the account balance.
+func (ps positionSizing) compounds() bool {
+	return ps.model == "percentRisk" || ps.model == "kelly"
+}
+
+// sizingAccount sizes the trades of one strategy in order and tracks its balance.
+type sizingAccount struct {
+	sizing  *positionSizing
+	balance float64
+	// wins, losses and their summed R multiples estimate the Kelly fraction from the
+	// settled trades.
+	wins, losses int
+	winR, lossR  float64
+}
+
+func (ps *positionSizing) newAccount() sizingAccount {
+	return sizingAccount{sizing: ps, balance: ps.startingBalance}
+}
+
+// settle returns the money result of a trade that made the given pips, negative for a
+// loss, with the given SL distance and books it on the account.
+func (a *sizingAccount) settle(trade Trade, pips, slPips float64) float64 {
+	ps := a.sizing
+	var pnl float64
+	if ps.model == "fixedLot" {
+		pipValue := 1.0
+		if value, ok := ps.pipValues[trade.String("instrument")].(float64); ok {
+			pipValue = value
+		}
+		pnl = pips * ps.lotSize * pipValue
+	} else {
+		pnl = pips / slPips * a.risk()
+	}
+
+	a.balance += pnl
+	if r := pips / slPips; r > 0 {
+		a.wins++
+		a.winR += r
+	} else {
+		a.losses++
+		a.lossR -= r
+	}
+	return pnl
+}
+
+// blown reports whether a compounding account has nothing left to risk, so the remaining
+// trades of the strategy are not taken.
+func (a *sizingAccount) blown() bool {
+	return a.sizing.compounds() && a.balance <= 0
+}
+
+// risk is the amount the next trade risks.
+func (a *sizingAccount) risk() float64 {
+	ps := a.sizing
+	if ps.model == "fixedRisk" {
+		return ps.riskAmount
+	}
+	if a.blown() {
+		return 0
+	}
+	percent := ps.riskPercent
+	if ps.model == "kelly" && a.wins+a.losses >= ps.kellyWarmup {
+		percent = 0
+		if a.wins > 0 {
+			winRate := float64(a.wins) / float64(a.wins+a.losses)
+			kelly := winRate
+			if a.lossR > 0 {
+				payoff := (a.winR / float64(a.wins)) / (a.lossR / float64(a.losses))
+				kelly -= (1 - winRate) / payoff
+			}
+			percent = math.Min(math.Max(kelly*ps.kellyFraction*100, 0), ps.maxRiskPercent)
+		}
+	}
+	return a.balance * percent / 100
+}
@@ -0,0 +1,99 @@
+package optimizer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSizingAccountRisk(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting map[string]interface{}
+		account sizingAccount
+		want    float64
+	}{
+		{"fixed risk default", nil, sizingAccount{balance: 10000}, 100},
+		{"fixed risk amount", map[string]interface{}{"model": "fixedRisk", "riskAmount": 250.0}, sizingAccount{balance: 10000}, 250},
+		{"percent risk", map[string]interface{}{"model": "percentRisk", "riskPercent": 2.0}, sizingAccount{balance: 5000}, 100},
+		{"percent risk blown account", map[string]interface{}{"model": "percentRisk"}, sizingAccount{balance: -50}, 0},
+		{"kelly warmup uses risk percent", map[string]interface{}{"model": "kelly", "riskPercent": 1.0}, sizingAccount{balance: 10000, wins: 5, losses: 5, winR: 10, lossR: 5}, 100},
+		// Win rate 0.6 and payoff 2 give a Kelly fraction of 0.4; half of it is 20%.
+		{"kelly capped", map[string]interface{}{"model": "kelly"}, sizingAccount{balance: 10000, wins: 30, losses: 20, winR: 60, lossR: 20}, 200},
+		{"kelly uncapped", map[string]interface{}{"model": "kelly", "maxRiskPercent": 50.0}, sizingAccount{balance: 10000, wins: 30, losses: 20, winR: 60, lossR: 20}, 2000},
+		{"kelly without edge", map[string]interface{}{"model": "kelly"}, sizingAccount{balance: 10000, wins: 20, losses: 30, winR: 20, lossR: 30}, 0},
+		{"kelly without wins", map[string]interface{}{"model": "kelly"}, sizingAccount{balance: 10000, losses: 30, lossR: 30}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sizing := newPositionSizing(map[string]interface{}{"positionSizing": tt.setting})
+			account := tt.account
+			account.sizing = &sizing
+			if got := account.risk(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("risk = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizingAccountSettle(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"instrument", StringColumn}}, []interface{}{"DAX"})
+	tests := []struct {
+		name    string
+		setting map[string]interface{}
+		pips    []float64
+		want    []float64
+	}{
+		{"fixed risk", nil, []float64{20, -10}, []float64{200, -100}},
+		{"percent risk compounds", map[string]interface{}{"model": "percentRisk"}, []float64{20, -10}, []float64{200, -102}},
+		{"fixed lot with pip value", map[string]interface{}{"model": "fixedLot", "lotSize": 2.0, "pipValues": map[string]interface{}{"DAX": 5.0}}, []float64{20, -10}, []float64{200, -100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sizing := newPositionSizing(map[string]interface{}{"positionSizing": tt.setting})
+			account := sizing.newAccount()
+			for i, pips := range tt.pips {
+				if got := account.settle(trades[0], pips, 10); math.Abs(got-tt.want[i]) > 1e-9 {
+					t.Errorf("trade %d: pnl = %g, want %g", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePositionSizing(t *testing.T) {
+	tests := []struct {
+		settings map[string]interface{}
+		wantErr  bool
+	}{
+		{map[string]interface{}{}, false},
+		{map[string]interface{}{"positionSizing": map[string]interface{}{"model": "kelly"}}, false},
+		{map[string]interface{}{"positionSizing": map[string]interface{}{"model": "martingale"}}, true},
+		{map[string]interface{}{"positionSizing": "kelly"}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidatePositionSizing(tt.settings); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePositionSizing(%v) = %v, want error %v", tt.settings, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSizingAccountBlown(t *testing.T) {
+	tests := []struct {
+		model   string
+		balance float64
+		blown   bool
+	}{
+		{"percentRisk", 0, true},
+		{"kelly", -10, true},
+		{"percentRisk", 10, false},
+		{"fixedRisk", -10, false},
+		{"fixedLot", -10, false},
+	}
+	for _, tt := range tests {
+		sizing := newPositionSizing(map[string]interface{}{"positionSizing": map[string]interface{}{"model": tt.model}})
+		account := sizingAccount{sizing: &sizing, balance: tt.balance}
+		if got := account.blown(); got != tt.blown {
+			t.Errorf("%s with balance %g: blown = %v, want %v", tt.model, tt.balance, got, tt.blown)
+		}
+	}
+}
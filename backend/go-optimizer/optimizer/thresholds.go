@@ -0,0 +1,202 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ThresholdSource describes how the thresholds of a numeric criterion are obtained.
+// Type is one of "quantile", "equalWidth" or "list".
+type ThresholdSource struct {
+	Type          string
+	Count         int
+	Values        []interface{}
+	IncludeBounds bool
+}
+
+// Shorthands accepted in place of a full source definition, e.g. "Candle_Size": "deciles".
+var thresholdSourceAliases = map[string]ThresholdSource{
+	"deciles":   {Type: "quantile", Count: 10},
+	"quintiles": {Type: "quantile", Count: 5},
+	"quartiles": {Type: "quantile", Count: 4},
+}
+
+// parseThresholdSource converts a raw source definition from the catalog or the settings.
+func parseThresholdSource(raw interface{}) (ThresholdSource, error) {
+	switch def := raw.(type) {
+	case string:
+		source, ok := thresholdSourceAliases[def]
+		if !ok {
+			return ThresholdSource{}, fmt.Errorf("unknown threshold source %q", def)
+		}
+		return source, nil
+	case map[string]interface{}:
+		source := ThresholdSource{}
+		source.Type, _ = def["type"].(string)
+		if count, ok := def["count"].(float64); ok {
+			source.Count = int(count)
+		}
+		source.Values, _ = def["values"].([]interface{})
+		source.IncludeBounds, _ = def["includeBounds"].(bool)
+
+		switch source.Type {
+		case "quantile", "equalWidth":
+			if source.Count < 2 {
+				return ThresholdSource{}, fmt.Errorf("threshold source %q needs a count of at least 2", source.Type)
+			}
+		case "list":
+			if len(source.Values) == 0 {
+				return ThresholdSource{}, fmt.Errorf("threshold source 'list' needs values")
+			}
+		default:
+			return ThresholdSource{}, fmt.Errorf("unknown threshold source type %q", source.Type)
+		}
+		return source, nil
+	}
+	return ThresholdSource{}, fmt.Errorf("invalid threshold source definition: %v", raw)
+}
+
+// ApplyThresholdSources replaces the hand-picked thresholds of numeric criteria by thresholds
+// derived from the distribution of the pre-filtered trades. The source comes from the
+// "thresholdSources" setting (keyed by column header) or from the criterion itself.
+//
+// The combinations are shared by all datasets, while the distributions of different
+// instruments and timeframes are not comparable, so with several datasets only "list"
+// sources apply; the others keep the configured thresholds. Every source that is ignored or
+// cannot be applied is returned as a warning.
+func ApplyThresholdSources(criteria []CombinationCriterion, datasets []Dataset, settings map[string]interface{}) ([]CombinationCriterion, []string) {
+	overrides, _ := settings["thresholdSources"].(map[string]interface{})
+	var trades []Trade
+	if len(datasets) == 1 {
+		trades = datasets[0].Trades
+	}
+	var warnings []string
+
+	for i := range criteria {
+		criterion := &criteria[i]
+		if criterion.Type != "numericRange" {
+			continue
+		}
+
+		rawSource := criterion.ThresholdSource
+		if override, ok := overrides[criterion.ColumnHeader]; ok {
+			rawSource = override
+		}
+		if rawSource == nil {
+			continue
+		}
+
+		source, err := parseThresholdSource(rawSource)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Threshold source for %s is ignored: %v; its configured thresholds are kept.", criterion.ColumnHeader, err))
+			continue
+		}
+		if source.Type != "list" && len(datasets) > 1 {
+			warnings = append(warnings, fmt.Sprintf("Threshold source %q for %s is ignored with %d datasets; its configured thresholds are kept.", source.Type, criterion.ColumnHeader, len(datasets)))
+			continue
+		}
+
+		thresholds, err := deriveThresholds(source, criterion, trades)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Thresholds for %s could not be derived from its %s source: %v; its configured thresholds are kept.", criterion.ColumnHeader, source.Type, err))
+			continue
+		}
+
+		// Keep the "any" choice if the original catalog entry offered it.
+		for _, t := range criterion.Thresholds {
+			if t == nil {
+				thresholds = append(thresholds, nil)
+				break
+			}
+		}
+		criterion.Thresholds = thresholds
+		debugLog.Printf("Derived %d thresholds for %s from %s source: %v", len(thresholds), criterion.ColumnHeader, source.Type, thresholds)
+	}
+	return criteria, warnings
+}
+
+// deriveThresholds computes the threshold list of a single criterion.
+func deriveThresholds(source ThresholdSource, criterion *CombinationCriterion, trades []Trade) ([]interface{}, error) {
+	if source.Type == "list" {
+		var thresholds []interface{}
+		for _, v := range source.Values {
+			if v != nil {
+				thresholds = append(thresholds, v)
+			}
+		}
+		return thresholds, nil
+	}
+
+	values := collectColumnValues(trades, criterion.ColumnHeader)
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no numeric values found in the pre-filtered trades")
+	}
+	sort.Float64s(values)
+
+	// Closed ranges need the outer edges to express "from the lowest value up to X".
+	includeBounds := source.IncludeBounds || criterion.Mode == "PERMUTATION" || criterion.Mode == "OUTSIDE"
+
+	var cuts []float64
+	switch source.Type {
+	case "quantile":
+		for k := 1; k < source.Count; k++ {
+			cuts = append(cuts, quantile(values, float64(k)/float64(source.Count)))
+		}
+	case "equalWidth":
+		minVal, maxVal := values[0], values[len(values)-1]
+		width := (maxVal - minVal) / float64(source.Count)
+		for k := 1; k < source.Count; k++ {
+			cuts = append(cuts, minVal+float64(k)*width)
+		}
+	}
+	if includeBounds {
+		cuts = append([]float64{values[0]}, cuts...)
+		cuts = append(cuts, values[len(values)-1])
+	}
+
+	// Round for readable output and drop duplicates caused by clustered values.
+	var thresholds []interface{}
+	seen := make(map[float64]struct{})
+	for _, c := range cuts {
+		rounded := math.Round(c*100) / 100
+		if _, exists := seen[rounded]; exists {
+			continue
+		}
+		seen[rounded] = struct{}{}
+		thresholds = append(thresholds, rounded)
+	}
+	return thresholds, nil
+}
+
+// collectColumnValues returns the numeric values of a (possibly direction-aware) column.
+func collectColumnValues(trades []Trade, columnHeader string) []float64 {
+	var values []float64
+	resolver := newColumnResolver(columnHeader)
+	for _, trade := range trades {
+		column, negate := resolver.resolve(trade)
+		if column == nil {
+			return nil
+		}
+		val, ok := column.Number(trade.Row)
+		if !ok {
+			return nil
+		}
+		if negate {
+			val = -val
+		}
+		values = append(values, val)
+	}
+	return values
+}
+
+// quantile returns the q-quantile of sorted values using linear interpolation.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[upper]-sorted[lower])
+}
@@ -0,0 +1,37 @@
+package optimizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyThresholdSourcesWarnings(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"Candle_Size", FloatColumn}, {"Label", StringColumn}},
+		[]interface{}{1.0, "a"}, []interface{}{2.0, "b"}, []interface{}{3.0, "c"}, []interface{}{4.0, "d"},
+	)
+	criterion := func(column string, source interface{}) CombinationCriterion {
+		return CombinationCriterion{ColumnHeader: column, Type: "numericRange", Mode: "MIN", Thresholds: []interface{}{5.0, nil}, ThresholdSource: source}
+	}
+	tests := []struct {
+		name       string
+		criterion  CombinationCriterion
+		thresholds []interface{}
+		warnings   int
+	}{
+		{"list", criterion("Candle_Size", map[string]interface{}{"type": "list", "values": []interface{}{1.0, 2.0}}), []interface{}{1.0, 2.0, nil}, 0},
+		{"quartiles", criterion("Candle_Size", "quartiles"), []interface{}{1.75, 2.5, 3.25, nil}, 0},
+		{"unknown source", criterion("Candle_Size", "octiles"), []interface{}{5.0, nil}, 1},
+		{"no numeric values", criterion("Label", "quartiles"), []interface{}{5.0, nil}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criteria, warnings := ApplyThresholdSources([]CombinationCriterion{tt.criterion}, []Dataset{{Trades: trades}}, map[string]interface{}{})
+			if !reflect.DeepEqual(criteria[0].Thresholds, tt.thresholds) {
+				t.Errorf("thresholds = %v, want %v", criteria[0].Thresholds, tt.thresholds)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("warnings = %q, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+}
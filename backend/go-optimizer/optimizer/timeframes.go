@@ -0,0 +1,77 @@
+package optimizer
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// TimeframeComparison is one row of a result's comparison table: the combination
+// evaluated on the same instrument in another timeframe.
+type TimeframeComparison struct {
+	Timeframe  string
+	TradeCount int
+	// OverallScore is nil if the combination is not valid in this timeframe.
+	OverallScore *float64
+	// Strategy is the best strategy of the compared result; Metrics are its metrics here.
+	Strategy string
+	Metrics  *StrategyMetrics
+}
+
+// MarshalJSON writes an infinite profit factor as 9999, like Result.MarshalJSON.
+func (c TimeframeComparison) MarshalJSON() ([]byte, error) {
+	var metrics interface{}
+	if c.Metrics != nil {
+		metrics = metricsToJSON(*c.Metrics)
+	}
+	return json.Marshal(map[string]interface{}{
+		"timeframe":    c.Timeframe,
+		"tradeCount":   c.TradeCount,
+		"overallScore": c.OverallScore,
+		"strategy":     c.Strategy,
+		"metrics":      metrics,
+	})
+}
+
+// AttachTimeframeComparisons evaluates each final result's combination on every timeframe
+// of its instrument, so an edge specific to one timeframe can be told from a robust one.
+// It does nothing unless the run loads several timeframes of an instrument ("dataSheetNames").
+func AttachTimeframeComparisons(results []Result, datasets []Dataset, settings map[string]interface{}) {
+	timeframes := make(map[string][]Dataset)
+	for _, dataset := range datasets {
+		timeframes[dataset.Instrument] = append(timeframes[dataset.Instrument], dataset)
+	}
+
+	for i := range results {
+		result := &results[i]
+		instrumentDatasets := timeframes[result.Instrument]
+		if result.InstrumentScores != nil || len(instrumentDatasets) < 2 {
+			continue
+		}
+
+		strategy := bestStrategy(result.StrategyScores)
+		for _, dataset := range instrumentDatasets {
+			evaluated, ok := EvaluateCombination(result.Combination, dataset.Trades, settings, NewStrategyConstraints(settings, dataset.Trades), nil)
+			row := TimeframeComparison{Timeframe: dataset.Timeframe, TradeCount: evaluated.OverallTradeCount, Strategy: strategy}
+			if ok {
+				score := evaluated.OverallScore
+				row.OverallScore = &score
+				if metrics, ok := evaluated.Metrics[strategy]; ok {
+					row.Metrics = &metrics
+				}
+			}
+			result.TimeframeComparison = append(result.TimeframeComparison, row)
+		}
+	}
+}
+
+// bestStrategy returns the name of the strategy with the highest finite score.
+func bestStrategy(scores map[string]float64) string {
+	best, bestScore := "", math.Inf(-1)
+	for _, strategy := range Strategies {
+		name := strategy.Name
+		if score, ok := scores[name]; ok && !math.IsInf(score, 0) && score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,120 @@
+package optimizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// EquityPoint is the cumulative net profit of a strategy after one of its trades.
+type EquityPoint struct {
+	TradeID   int     `json:"tradeId"`
+	Timestamp int64   `json:"timestamp"`
+	Equity    float64 `json:"equity"`
+}
+
+// TradeDetails lists the trades a final result's combination takes and the equity curve of
+// every strategy it scores with.
+type TradeDetails struct {
+	ResultIndex  int                      `json:"resultIndex"`
+	TradeIDs     []int                    `json:"tradeIds"`
+	EquityCurves map[string][]EquityPoint `json:"equityCurves"`
+}
+
+// tradeOutcome is a trade counted by a strategy, its result in pips with the SL distance
+// and its money result.
+type tradeOutcome struct {
+	trade        Trade
+	pips, slPips float64
+	pnl          float64
+}
+
+// strategyOutcomes returns the trades of a filtered set that a strategy counts, with their
+// P&L, as CalculateMetrics counts them.
+func strategyOutcomes(filteredTrades []Trade, ltaCombination bool, candleSizeTpRatio float64, strategy Strategy, settings map[string]interface{}) []tradeOutcome {
+	if strategy.LTA != ltaCombination {
+		return nil
+	}
+	limits := newOutcomeLimits(settings, candleSizeTpRatio)
+	sizing := newPositionSizing(settings)
+	account := sizing.newAccount()
+
+	var outcomes []tradeOutcome
+	for _, trade := range filteredTrades {
+		if account.blown() {
+			break
+		}
+		if !setupApplies(trade.String("Setup"), strategy.Setups, strategy.ExcludedSetups) {
+			continue
+		}
+		pips, slPips, ok := evaluateTrade(trade, strategy, limits)
+		if !ok {
+			continue
+		}
+		outcomes = append(outcomes, tradeOutcome{trade: trade, pips: pips, slPips: slPips, pnl: account.settle(trade, pips, slPips)})
+	}
+	return outcomes
+}
+
+// BuildTradeDetails re-applies each final result's combination to its dataset and collects
+// its trade ids and the equity curve of every strategy with a positive score, in
+// chronological order. Cross-instrument results get none.
+func BuildTradeDetails(results []Result, datasets []Dataset, settings map[string]interface{}) []TradeDetails {
+	var details []TradeDetails
+	for i, result := range results {
+		trades := resultTrades(result, datasets)
+		if trades == nil {
+			continue
+		}
+		filteredTrades, ltaCombination, candleSizeTpRatio := ApplyFilters(trades, result.Combination)
+
+		detail := TradeDetails{ResultIndex: i, TradeIDs: make([]int, 0, len(filteredTrades)), EquityCurves: make(map[string][]EquityPoint)}
+		for _, trade := range filteredTrades {
+			detail.TradeIDs = append(detail.TradeIDs, trade.ID())
+		}
+		for _, strategy := range Strategies {
+			name := strategy.Name
+			if score, ok := result.StrategyScores[name]; !ok || score <= 0 {
+				continue
+			}
+			outcomes := strategyOutcomes(filteredTrades, ltaCombination, candleSizeTpRatio, strategy, settings)
+			sort.SliceStable(outcomes, func(a, b int) bool {
+				return tradeTimestamp(outcomes[a].trade) < tradeTimestamp(outcomes[b].trade)
+			})
+			curve := make([]EquityPoint, 0, len(outcomes))
+			equity := 0.0
+			for _, outcome := range outcomes {
+				equity += outcome.pnl
+				curve = append(curve, EquityPoint{TradeID: outcome.trade.ID(), Timestamp: tradeTimestamp(outcome.trade), Equity: equity})
+			}
+			detail.EquityCurves[name] = curve
+		}
+		details = append(details, detail)
+	}
+	return details
+}
+
+// AttachTradeDetails adds the trade details to the results they belong to.
+func AttachTradeDetails(results []Result, details []TradeDetails) {
+	for _, detail := range details {
+		results[detail.ResultIndex].TradeIDs = detail.TradeIDs
+		results[detail.ResultIndex].EquityCurves = detail.EquityCurves
+	}
+}
+
+// WriteTradeDetailsFile writes the trade details to a side file as a JSON array keyed by
+// result index.
+func WriteTradeDetailsFile(path string, details []TradeDetails) error {
+	if details == nil {
+		details = []TradeDetails{}
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("could not encode trade details: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("could not write trade details: %w", err)
+	}
+	return nil
+}
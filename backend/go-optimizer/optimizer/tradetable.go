@@ -0,0 +1,275 @@
+package optimizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColumnKind is the storage type of a trade column.
+type ColumnKind int
+
+const (
+	BoolColumn ColumnKind = iota
+	IntColumn
+	FloatColumn
+	StringColumn
+)
+
+// TradeColumn holds the values of one column for every row of a TradeTable.
+// Only the slice matching Kind is populated.
+type TradeColumn struct {
+	Name    string
+	Kind    ColumnKind
+	Bools   []bool
+	Ints    []int
+	Floats  []float64
+	Strings []string
+}
+
+// TradeTable is the columnar representation of the trade table. Columns are keyed by
+// their database name, so criteria and strategies can address any column by name.
+type TradeTable struct {
+	columns map[string]*TradeColumn
+	names   []string
+	rows    int
+}
+
+// NewTradeTable creates an empty table.
+func NewTradeTable() *TradeTable {
+	return &TradeTable{columns: make(map[string]*TradeColumn)}
+}
+
+// AddColumn adds a column sized to the current number of rows and returns it, so
+// derived columns can be filled in by row index. An existing column of the same
+// name is replaced.
+func (t *TradeTable) AddColumn(name string, kind ColumnKind) *TradeColumn {
+	col := &TradeColumn{Name: name, Kind: kind}
+	switch kind {
+	case BoolColumn:
+		col.Bools = make([]bool, t.rows)
+	case IntColumn:
+		col.Ints = make([]int, t.rows)
+	case FloatColumn:
+		col.Floats = make([]float64, t.rows)
+	case StringColumn:
+		col.Strings = make([]string, t.rows)
+	}
+	if _, exists := t.columns[name]; !exists {
+		t.names = append(t.names, name)
+	}
+	t.columns[name] = col
+	return col
+}
+
+// AppendRow appends one row. The values must follow the order in which the columns
+// were added; nil values are stored as the zero value of the column.
+func (t *TradeTable) AppendRow(values []interface{}) error {
+	if len(values) != len(t.names) {
+		return fmt.Errorf("row has %d values, table has %d columns", len(values), len(t.names))
+	}
+	for i, name := range t.names {
+		if err := t.columns[name].append(values[i]); err != nil {
+			return err
+		}
+	}
+	t.rows++
+	return nil
+}
+
+// Column returns the column with the given name, or nil.
+func (t *TradeTable) Column(name string) *TradeColumn {
+	return t.columns[name]
+}
+
+// HasColumn reports whether the table carries the given column.
+func (t *TradeTable) HasColumn(name string) bool {
+	_, ok := t.columns[name]
+	return ok
+}
+
+// ColumnNames returns the column names in load order.
+func (t *TradeTable) ColumnNames() []string {
+	return t.names
+}
+
+// Len returns the number of rows.
+func (t *TradeTable) Len() int {
+	return t.rows
+}
+
+// Trades returns a row view for every row of the table.
+func (t *TradeTable) Trades() []Trade {
+	trades := make([]Trade, t.rows)
+	for i := range trades {
+		trades[i] = Trade{table: t, Row: i}
+	}
+	return trades
+}
+
+func (c *TradeColumn) append(value interface{}) error {
+	switch c.Kind {
+	case BoolColumn:
+		v, _ := value.(bool)
+		c.Bools = append(c.Bools, v)
+	case IntColumn:
+		var v int
+		switch n := value.(type) {
+		case int16:
+			v = int(n)
+		case int32:
+			v = int(n)
+		case int64:
+			v = int(n)
+		case int:
+			v = n
+		case nil:
+		default:
+			return fmt.Errorf("column %s: unexpected integer value %T", c.Name, value)
+		}
+		c.Ints = append(c.Ints, v)
+	case FloatColumn:
+		var v float64
+		switch n := value.(type) {
+		case float32:
+			v = float64(n)
+		case float64:
+			v = n
+		case nil:
+		default:
+			return fmt.Errorf("column %s: unexpected float value %T", c.Name, value)
+		}
+		c.Floats = append(c.Floats, v)
+	case StringColumn:
+		v, _ := value.(string)
+		c.Strings = append(c.Strings, v)
+	}
+	return nil
+}
+
+// Number returns the value of a numeric column as float64.
+func (c *TradeColumn) Number(row int) (float64, bool) {
+	switch c.Kind {
+	case IntColumn:
+		return float64(c.Ints[row]), true
+	case FloatColumn:
+		return c.Floats[row], true
+	}
+	return 0, false
+}
+
+// Value returns the value of any column as an interface.
+func (c *TradeColumn) Value(row int) interface{} {
+	switch c.Kind {
+	case BoolColumn:
+		return c.Bools[row]
+	case IntColumn:
+		return c.Ints[row]
+	case FloatColumn:
+		return c.Floats[row]
+	default:
+		return c.Strings[row]
+	}
+}
+
+// Trade is a lightweight view of one row of a TradeTable.
+type Trade struct {
+	table *TradeTable
+	Row   int
+}
+
+// Table returns the table the trade belongs to.
+func (t Trade) Table() *TradeTable {
+	return t.table
+}
+
+// Column returns the named column of the trade's table, or nil.
+func (t Trade) Column(name string) *TradeColumn {
+	return t.table.columns[name]
+}
+
+// Bool returns a boolean column value; missing or non-boolean columns read as false.
+func (t Trade) Bool(name string) bool {
+	if col := t.table.columns[name]; col != nil && col.Kind == BoolColumn {
+		return col.Bools[t.Row]
+	}
+	return false
+}
+
+// Int returns an integer column value; missing or non-integer columns read as 0.
+func (t Trade) Int(name string) int {
+	if col := t.table.columns[name]; col != nil && col.Kind == IntColumn {
+		return col.Ints[t.Row]
+	}
+	return 0
+}
+
+// Float returns a numeric column value; missing or non-numeric columns read as 0.
+func (t Trade) Float(name string) float64 {
+	if col := t.table.columns[name]; col != nil {
+		v, _ := col.Number(t.Row)
+		return v
+	}
+	return 0
+}
+
+// String returns a string column value; missing or non-string columns read as "".
+func (t Trade) String(name string) string {
+	if col := t.table.columns[name]; col != nil && col.Kind == StringColumn {
+		return col.Strings[t.Row]
+	}
+	return ""
+}
+
+// ID returns the database id of the trade.
+func (t Trade) ID() int {
+	return t.Int("id")
+}
+
+// columnResolver resolves a criterion key to the column it refers to for a trade. A "a|b"
+// key is direction-aware: BUY trades read column a, SELL trades read column b. A leading
+// "-" on a column name negates its value, so "X|-X" reads a signed column relative to
+// the trade's direction. The key is parsed once; the columns are looked up again only
+// when a trade comes from another table, so filtering pays no string or map work per trade.
+type columnResolver struct {
+	buyName, sellName     string
+	negateBuy, negateSell bool
+	directional           bool
+
+	table                *TradeTable
+	buy, sell, direction *TradeColumn
+}
+
+func newColumnResolver(key string) *columnResolver {
+	r := &columnResolver{buyName: key, sellName: key}
+	if buy, sell, ok := strings.Cut(key, "|"); ok {
+		r.buyName, r.sellName, r.directional = buy, sell, true
+	}
+	r.buyName, r.negateBuy = strings.CutPrefix(r.buyName, "-")
+	r.sellName, r.negateSell = strings.CutPrefix(r.sellName, "-")
+	return r
+}
+
+// resolve returns the column for the trade and whether to negate its value.
+func (r *columnResolver) resolve(t Trade) (*TradeColumn, bool) {
+	if t.table != r.table {
+		r.table = t.table
+		r.buy, r.sell = t.table.columns[r.buyName], t.table.columns[r.sellName]
+		r.direction = t.table.columns["Direction"]
+		if r.direction != nil && r.direction.Kind != StringColumn {
+			r.direction = nil
+		}
+	}
+	if !r.directional || (r.direction != nil && r.direction.Strings[t.Row] == "BUY") {
+		return r.buy, r.negateBuy
+	}
+	return r.sell, r.negateSell
+}
+
+// criterionColumnNames returns the table columns a criterion key reads.
+func criterionColumnNames(key string) []string {
+	var names []string
+	for _, part := range strings.Split(key, "|") {
+		names = append(names, strings.TrimPrefix(part, "-"))
+	}
+	return names
+}
@@ -0,0 +1,91 @@
+package optimizer
+
+import "testing"
+
+type testColumn struct {
+	name string
+	kind ColumnKind
+}
+
+// testTrades builds a trade table from rows given in column order and returns its trades.
+func testTrades(t *testing.T, columns []testColumn, rows ...[]interface{}) []Trade {
+	t.Helper()
+	table := NewTradeTable()
+	for _, column := range columns {
+		table.AddColumn(column.name, column.kind)
+	}
+	for _, row := range rows {
+		if err := table.AppendRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return table.Trades()
+}
+
+func TestTradeTableAppendRow(t *testing.T) {
+	trades := testTrades(t,
+		[]testColumn{{"id", IntColumn}, {"Is_Win", BoolColumn}, {"Candle_Size", FloatColumn}, {"Setup", StringColumn}},
+		[]interface{}{int64(7), true, 12.5, "A"},
+		[]interface{}{int32(8), nil, float32(3), nil},
+	)
+	if len(trades) != 2 {
+		t.Fatalf("got %d trades, want 2", len(trades))
+	}
+	if trades[0].ID() != 7 || !trades[0].Bool("Is_Win") || trades[0].Float("Candle_Size") != 12.5 || trades[0].String("Setup") != "A" {
+		t.Errorf("first row read back wrong")
+	}
+	if trades[1].ID() != 8 || trades[1].Bool("Is_Win") || trades[1].Float("Candle_Size") != 3 || trades[1].String("Setup") != "" {
+		t.Errorf("second row read back wrong")
+	}
+
+	table := trades[0].Table()
+	if err := table.AppendRow([]interface{}{1}); err == nil {
+		t.Errorf("AppendRow accepted a short row")
+	}
+	if err := table.AppendRow([]interface{}{"x", true, 1.0, "A"}); err == nil {
+		t.Errorf("AppendRow accepted a string in an integer column")
+	}
+}
+
+func TestColumnResolver(t *testing.T) {
+	columns := []testColumn{{"Direction", StringColumn}, {"Run", IntColumn}, {"Up", FloatColumn}, {"Down", FloatColumn}}
+	buy := testTrades(t, columns, []interface{}{"BUY", 3, 1.0, 2.0})[0]
+	sell := testTrades(t, columns, []interface{}{"SELL", 3, 1.0, 2.0})[0]
+
+	tests := []struct {
+		key    string
+		trade  Trade
+		column string
+		negate bool
+	}{
+		{"Run", buy, "Run", false},
+		{"-Run", sell, "Run", true},
+		{"Up|Down", buy, "Up", false},
+		{"Up|Down", sell, "Down", false},
+		{"Run|-Run", buy, "Run", false},
+		{"Run|-Run", sell, "Run", true},
+		{"-Run|Run", buy, "Run", true},
+		{"Missing", buy, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+" "+tt.trade.String("Direction"), func(t *testing.T) {
+			column, negate := newColumnResolver(tt.key).resolve(tt.trade)
+			want := tt.trade.Column(tt.column)
+			if column != want || negate != tt.negate {
+				t.Errorf("resolve = (%v, %v), want column %q negated %v", column != nil, negate, tt.column, tt.negate)
+			}
+		})
+	}
+
+	// One resolver follows trades from different tables.
+	resolver := newColumnResolver("Up|Down")
+	for _, trade := range []Trade{buy, sell, buy} {
+		want := "Up"
+		if trade.String("Direction") == "SELL" {
+			want = "Down"
+		}
+		if column, _ := resolver.resolve(trade); column != trade.Column(want) {
+			t.Errorf("%s trade resolved to the wrong column", trade.String("Direction"))
+		}
+	}
+}
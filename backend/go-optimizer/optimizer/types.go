@@ -3,65 +3,11 @@ package optimizer
 import (
 	"encoding/json"
 	"math"
+	"time"
 )
 
 // --- Struct Definitions ---
 
-type Trade struct {
-	ID                              int     `db:"id" json:"-"`
-	Time                            string  `db:"Time"`
-	Setup                           string  `db:"Setup"`
-	Direction                       string  `db:"Direction"`
-	Entered                         bool    `db:"Entered"`
-	Canceled_After_Candles          int     `db:"Canceled_After_Candles"`
-	Breakout_Candle_Count           int     `db:"Breakout_Candle_Count"`
-	Candle_Size                     float64 `db:"Candle_Size"`
-	Breakout_Distance               float64 `db:"Breakout_Distance"`
-	Entry_Distance                  float64 `db:"Entry_Distance"`
-	Entry_Candle_Has_Wick           bool    `db:"Entry_Candle_Has_Wick"`
-	Closed_In_LTA                   bool    `db:"Closed_In_LTA"`
-	Gaussian_Trend_1                bool    `db:"Gaussian_Trend_1"`
-	Gaussian_Trend_4                bool    `db:"Gaussian_Trend_4"`
-	Gaussian_Trend_7                bool    `db:"Gaussian_Trend_7"`
-	TP_1RR_PW_WIN                   bool    `db:"TP_1RR_PW_WIN"`
-	TP_1RR_STR_WIN                  bool    `db:"TP_1RR_STR_WIN"`
-	TP_SR_LTA_SL_PW_WIN             bool    `db:"TP_SR_LTA_SL_PW_WIN"`
-	TP_SR_LTA_SL_STR_WIN            bool    `db:"TP_SR_LTA_SL_STR_WIN"`
-	TP_SR_NEAREST_SL_PW_WIN         bool    `db:"TP_SR_NEAREST_SL_PW_WIN"`
-	TP_SR_NEAREST_SL_STR_WIN        bool    `db:"TP_SR_NEAREST_SL_STR_WIN"`
-	TP_SR_STATIC_SL_PW_WIN          bool    `db:"TP_SR_STATIC_SL_PW_WIN"`
-	TP_SR_STATIC_SL_STR_WIN         bool    `db:"TP_SR_STATIC_SL_STR_WIN"`
-	TP_SR_CURRENT_PW_WIN            bool    `db:"TP_SR_CURRENT_PW_WIN"`
-	TP_SR_CURRENT_STR_WIN           bool    `db:"TP_SR_CURRENT_STR_WIN"`
-	TP_1RR_PW_PIPS                  float64 `db:"TP_1RR_PW_PIPS"`
-	TP_1RR_STR_PIPS                 float64 `db:"TP_1RR_STR_PIPS"`
-	TP_SR_LTA_PIPS                  float64 `db:"TP_SR_LTA_PIPS"`
-	TP_SR_NEAREST_PIPS              float64 `db:"TP_SR_NEAREST_PIPS"`
-	TP_SR_STATIC_PIPS               float64 `db:"TP_SR_STATIC_PIPS"`
-	TP_SR_CURRENT_PIPS              float64 `db:"TP_SR_CURRENT_PIPS"`
-	SL_PW_PIPS                      float64 `db:"SL_PW_PIPS"`
-	SL_STR_PIPS                     float64 `db:"SL_STR_PIPS"`
-	LTA_Range_Breakout              bool    `db:"LTA_Range_Breakout"`
-	Nearest_Range_Breakout          bool    `db:"Nearest_Range_Breakout"`
-	Static_Range_Breakout           bool    `db:"Static_Range_Breakout"`
-	Current_Range_Breakout          bool    `db:"Current_Range_Breakout"`
-	M10_Candle                      bool    `db:"M10_Candle"`
-	M15_Candle                      bool    `db:"M15_Candle"`
-	M30_Candle                      bool    `db:"M30_Candle"`
-	H1_Candle                       bool    `db:"H1_Candle"`
-	H4_Candle                       bool    `db:"H4_Candle"`
-	D1_Candle                       bool    `db:"D1_Candle"`
-	M10_Candle_Open                 bool    `db:"M10_Candle_Open"`
-	M15_Candle_Open                 bool    `db:"M15_Candle_Open"`
-	M30_Candle_Open                 bool    `db:"M30_Candle_Open"`
-	H1_Candle_Open                  bool    `db:"H1_Candle_Open"`
-	H4_Candle_Open                  bool    `db:"H4_Candle_Open"`
-	D1_Candle_Open                  bool    `db:"D1_Candle_Open"`
-	S2_Previous_Support_Distance    float64 `db:"S2_Previous_Support_Distance"`
-	S2_Previous_Resistance_Distance float64 `db:"S2_Previous_Resistance_Distance"`
-	S3_Reversal_Candle_Size         float64 `db:"S3_Reversal_Candle_Size"`
-}
-
 type Configuration struct {
 	ID       int                    `json:"id"`
 	Name     string                 `json:"name"`
@@ -69,16 +15,31 @@ type Configuration struct {
 }
 
 type CombinationCriterion struct {
-	ColumnHeader string        `json:"columnHeader"`
-	Type         string        `json:"type"`
-	TestValues   []interface{} `json:"testValues"`
-	Thresholds   []interface{} `json:"thresholds"`
-	Mode         string        `json:"mode"`
+	ColumnHeader    string        `json:"columnHeader"`
+	Type            string        `json:"type"`
+	TestValues      []interface{} `json:"testValues"`
+	Thresholds      []interface{} `json:"thresholds"`
+	Mode            string        `json:"mode"`
+	ThresholdSource interface{}   `json:"thresholdSource,omitempty"`
+}
+
+// Dataset holds the prepared trades of one instrument and timeframe.
+type Dataset struct {
+	Instrument string
+	Timeframe  string
+	Trades     []Trade
+}
+
+// Label identifies the dataset in the output of multi-dataset runs.
+func (d Dataset) Label() string {
+	return d.Instrument + "/" + d.Timeframe
 }
 
 type InputData struct {
-	Config Configuration
-	Trades []Trade
+	Config   Configuration
+	Datasets []Dataset
+	// Rejections collects the rejection counts of all workers.
+	Rejections *RejectionCollector
 }
 
 type Combination map[string]interface{}
@@ -88,14 +49,81 @@ type StrategyMetrics struct {
 	ProfitFactor            float64 `json:"profitFactor"`
 	TotalTradesThisStrategy int     `json:"totalTradesThisStrategy"`
 	NetProfit               float64 `json:"netProfit"`
+	// MaxDrawdown is the largest peak-to-trough fall of the strategy's equity curve.
+	MaxDrawdown float64 `json:"maxDrawdown"`
+	// Buy and Sell hold the same metrics over the BUY and SELL trades only.
+	Buy  *StrategyMetrics `json:"buy,omitempty"`
+	Sell *StrategyMetrics `json:"sell,omitempty"`
+	// PerSetup holds the metrics per Setup value when the trades span several setups.
+	PerSetup map[string]*StrategyMetrics `json:"perSetup,omitempty"`
 }
 
 type Result struct {
-	Combination       Combination                `json:"combination"`
-	OverallScore      float64                    `json:"overallScore"`
-	OverallTradeCount int                        `json:"overallTradeCount"`
-	Metrics           map[string]StrategyMetrics `json:"metrics"`
-	StrategyScores    map[string]float64         `json:"strategyScores"`
+	Combination Combination `json:"combination"`
+	Direction   string      `json:"direction,omitempty"`
+	Setup       string      `json:"setup,omitempty"`
+	Instrument  string      `json:"instrument,omitempty"`
+	Timeframe   string      `json:"timeframe,omitempty"`
+	// InstrumentScores marks a cross-instrument result and holds its score per dataset.
+	InstrumentScores    map[string]float64         `json:"instrumentScores,omitempty"`
+	OverallScore        float64                    `json:"overallScore"`
+	OverallTradeCount   int                        `json:"overallTradeCount"`
+	Metrics             map[string]StrategyMetrics `json:"metrics"`
+	StrategyScores      map[string]float64         `json:"strategyScores"`
+	Aliases             []Combination              `json:"aliases,omitempty"`
+	AliasCount          int                        `json:"aliasCount,omitempty"`
+	EntryDelayHistogram []HistogramBucket          `json:"entryDelayHistogram,omitempty"`
+	TimeframeComparison []TimeframeComparison      `json:"timeframeComparison,omitempty"`
+	// Fingerprint is a hash of the dataset trades the combination selects.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// TradeIDs and EquityCurves are attached with the "tradeDetails": "attach" setting.
+	TradeIDs     []int                    `json:"tradeIds,omitempty"`
+	EquityCurves map[string][]EquityPoint `json:"equityCurves,omitempty"`
+	TradeSetHash uint64                   `json:"-"`
+}
+
+// RunOutput is the envelope printed to stdout: the results with the warnings, the run
+// summary and the optional sections of the run.
+type RunOutput struct {
+	Warnings   []string    `json:"warnings"`
+	Results    []Result    `json:"results"`
+	Portfolios []Portfolio `json:"portfolios,omitempty"`
+	// Overlap is the Jaccard trade overlap between the results, in result order.
+	Overlap [][]float64 `json:"overlap,omitempty"`
+	// TradeDetailsFile is the side file holding the trade details with "tradeDetails": "file".
+	TradeDetailsFile string      `json:"tradeDetailsFile,omitempty"`
+	Summary          *RunSummary `json:"summary,omitempty"`
+	// TradeDetails are the trade details of "tradeDetails": "file" when the optimizer stores
+	// the output itself; they go to the result row instead of a side file.
+	TradeDetails []TradeDetails `json:"-"`
+}
+
+// RunSummary describes the run and how its trades and combinations were narrowed down to
+// the results.
+type RunSummary struct {
+	Version    string           `json:"version"`
+	ConfigID   int              `json:"configId"`
+	ConfigHash string           `json:"configHash"`
+	JobID      string           `json:"jobId"`
+	Datasets   []DatasetSummary `json:"datasets"`
+	// TotalCombinations are generated, EvaluatedCombinations were processed by the workers,
+	// each on every dataset.
+	TotalCombinations     int             `json:"totalCombinations"`
+	EvaluatedCombinations uint64          `json:"evaluatedCombinations"`
+	RawResults            int             `json:"rawResults"`
+	FinalResults          int             `json:"finalResults"`
+	Rejections            RejectionCounts `json:"rejections"`
+	Workers               int             `json:"workers"`
+	StartedAt             time.Time       `json:"startedAt"`
+	DurationSeconds       float64         `json:"durationSeconds"`
+}
+
+// DatasetSummary counts the trades of a dataset before and after the predefined filters.
+type DatasetSummary struct {
+	Instrument     string `json:"instrument"`
+	Timeframe      string `json:"timeframe"`
+	LoadedTrades   int    `json:"loadedTrades"`
+	PreparedTrades int    `json:"preparedTrades"`
 }
 
 // MarshalJSON provides custom JSON serialization for the Result struct.
@@ -117,16 +145,7 @@ func (r Result) MarshalJSON() ([]byte, error) {
 
 	metricsForJSON := make(map[string]interface{})
 	for key, value := range r.Metrics {
-		pf := value.ProfitFactor
-		if math.IsInf(pf, 1) {
-			pf = 9999.0
-		}
-		metricsForJSON[key] = map[string]interface{}{
-			"winRate":                 value.WinRate,
-			"profitFactor":            pf,
-			"totalTradesThisStrategy": value.TotalTradesThisStrategy,
-			"netProfit":               value.NetProfit,
-		}
+		metricsForJSON[key] = metricsToJSON(value)
 	}
 
 	return json.Marshal(&struct {
@@ -141,3 +160,32 @@ func (r Result) MarshalJSON() ([]byte, error) {
 		Alias:          (*Alias)(&r),
 	})
 }
+
+// metricsToJSON converts metrics to a JSON-safe map, an infinite profit factor is written as 9999.
+func metricsToJSON(value StrategyMetrics) map[string]interface{} {
+	pf := value.ProfitFactor
+	if math.IsInf(pf, 1) {
+		pf = 9999.0
+	}
+	m := map[string]interface{}{
+		"winRate":                 value.WinRate,
+		"profitFactor":            pf,
+		"totalTradesThisStrategy": value.TotalTradesThisStrategy,
+		"netProfit":               value.NetProfit,
+		"maxDrawdown":             value.MaxDrawdown,
+	}
+	if value.Buy != nil {
+		m["buy"] = metricsToJSON(*value.Buy)
+	}
+	if value.Sell != nil {
+		m["sell"] = metricsToJSON(*value.Sell)
+	}
+	if len(value.PerSetup) > 0 {
+		perSetup := make(map[string]interface{})
+		for setup, setupMetrics := range value.PerSetup {
+			perSetup[setup] = metricsToJSON(*setupMetrics)
+		}
+		m["perSetup"] = perSetup
+	}
+	return m
+}
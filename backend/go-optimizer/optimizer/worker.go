@@ -8,7 +8,9 @@ import (
 )
 
 // CombinationWorker is the main processing goroutine. It receives jobs, applies filters,
-// calculates metrics, and sends valid results to the results channel.
+// calculates metrics, and sends valid results to the results channel. With several
+// datasets, a combination yields one result per dataset plus a cross-instrument result
+// if it is valid on all of them.
 func CombinationWorker(
 	id int,
 	jobs <-chan Combination,
@@ -23,6 +25,16 @@ func CombinationWorker(
 			debugLog.Printf("Worker %d PANIC: %v. Stack: %s", id, r, debug.Stack())
 		}
 	}()
+	multiDataset := len(inputData.Datasets) > 1
+	crossScoreMode, _ := inputData.Config.Settings["crossInstrumentScore"].(string)
+
+	constraints := make([]StrategyConstraints, len(inputData.Datasets))
+	for i, dataset := range inputData.Datasets {
+		constraints[i] = NewStrategyConstraints(inputData.Config.Settings, dataset.Trades)
+	}
+	rejections := &RejectionCounts{}
+	defer inputData.Rejections.merge(rejections)
+
 	jobCount := 0
 	for combo := range jobs {
 		jobCount += 1
@@ -30,45 +42,85 @@ func CombinationWorker(
 			debugLog.Printf("Worker %d processed %d jobs...", id, jobCount)
 		}
 
-		filteredTrades, ltaCombination, candleSizeTpRatio := ApplyFilters(inputData.Trades, combo)
-		if len(filteredTrades) < int(inputData.Config.Settings["minTradeCount"].(float64)) {
-			atomic.AddUint64(processedCounter, 1)
-			continue
+		var datasetResults []Result
+		for i, dataset := range inputData.Datasets {
+			result, ok := EvaluateCombination(combo, dataset.Trades, inputData.Config.Settings, constraints[i], rejections)
+			if !ok {
+				continue
+			}
+			if multiDataset {
+				result.Instrument, result.Timeframe = dataset.Instrument, dataset.Timeframe
+			}
+			datasetResults = append(datasetResults, result)
+			results <- result
 		}
 
-		metrics := CalculateMetrics(filteredTrades, ltaCombination, inputData.Config.Settings, candleSizeTpRatio)
-		if metrics == nil {
-			atomic.AddUint64(processedCounter, 1)
-			continue
+		if multiDataset && len(datasetResults) == len(inputData.Datasets) {
+			if cross, ok := CrossInstrumentResult(combo, datasetResults, inputData.Datasets, crossScoreMode); ok {
+				results <- cross
+			}
 		}
+		atomic.AddUint64(processedCounter, 1)
+	}
+}
 
-		scores := make(map[string]float64)
-		sumOfScores, scoredStrategies := 0.0, 0
-		weights := inputData.Config.Settings["rankingWeights"].(map[string]interface{})
+// EvaluateCombination filters the trades by the combination and scores every strategy
+// that meets its constraints. Discarded combinations and rejected strategies are counted
+// by reason in rejections, if not nil. It reports false if too few trades remain, no
+// strategy passes the minimum criteria or no strategy could be scored; the returned
+// result still carries the number of filtered trades in OverallTradeCount.
+func EvaluateCombination(combo Combination, trades []Trade, settings map[string]interface{}, constraints StrategyConstraints, rejections *RejectionCounts) (Result, bool) {
+	filteredTrades, ltaCombination, candleSizeTpRatio := ApplyFilters(trades, combo)
+	unscored := Result{OverallTradeCount: len(filteredTrades)}
+	if len(filteredTrades) < int(settings["minTradeCount"].(float64)) {
+		rejections.addCombination(rejectMinTradeCount, 1)
+		return unscored, false
+	}
 
-		for name, metric := range metrics {
-			score := CalculateCompositeScore(metric, weights)
-			scores[name] = score
-			if !math.IsInf(score, 0) {
-				sumOfScores += score
-				scoredStrategies++
-			}
-		}
+	metrics := CalculateMetrics(filteredTrades, ltaCombination, settings, candleSizeTpRatio)
+	if metrics == nil {
+		rejections.addCombination(rejectAllStrategies, 1)
+		return unscored, false
+	}
 
-		overallScore := math.Inf(-1)
-		if scoredStrategies > 0 {
-			overallScore = sumOfScores / float64(scoredStrategies)
-		}
+	scores := make(map[string]float64)
+	sumOfScores, scoredStrategies := 0.0, 0
+	weights := settings["rankingWeights"].(map[string]interface{})
 
-		if !math.IsInf(overallScore, 0) {
-			results <- Result{
-				Combination:       combo,
-				OverallScore:      overallScore,
-				OverallTradeCount: len(filteredTrades),
-				Metrics:           metrics,
-				StrategyScores:    scores,
-			}
+	for name, metric := range metrics {
+		// A strategy without trades is unscored anyway; its constraints are not counted.
+		if metric.TotalTradesThisStrategy == 0 {
+			scores[name] = math.Inf(-1)
+			continue
 		}
-		atomic.AddUint64(processedCounter, 1)
+		if reason := constraints.reject(name, metric); reason != "" {
+			rejections.addStrategy(name, reason, 1)
+			scores[name] = math.Inf(-1)
+			continue
+		}
+		score := CalculateCompositeScore(metric, weights)
+		scores[name] = score
+		if !math.IsInf(score, 0) {
+			sumOfScores += score
+			scoredStrategies++
+		}
+	}
+
+	overallScore := math.Inf(-1)
+	if scoredStrategies > 0 {
+		overallScore = sumOfScores / float64(scoredStrategies)
+	}
+	if math.IsInf(overallScore, 0) {
+		rejections.addCombination(rejectNoScoreStrategy, 1)
+		return unscored, false
 	}
+
+	return Result{
+		Combination:       combo,
+		OverallScore:      overallScore,
+		OverallTradeCount: len(filteredTrades),
+		Metrics:           metrics,
+		StrategyScores:    scores,
+		TradeSetHash:      TradeSetHash(filteredTrades, ltaCombination, candleSizeTpRatio),
+	}, true
 }
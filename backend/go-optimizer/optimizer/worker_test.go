@@ -0,0 +1,19 @@
+package optimizer
+
+import "testing"
+
+func TestEvaluateCombinationReportsTradeCountWhenDiscarded(t *testing.T) {
+	trades := testTrades(t, []testColumn{{"Setup", StringColumn}},
+		[]interface{}{"A"}, []interface{}{"A"}, []interface{}{"B"},
+	)
+	settings := map[string]interface{}{"minTradeCount": 5.0}
+	var rejections RejectionCounts
+
+	result, ok := EvaluateCombination(Combination{"Setup": "A"}, trades, settings, StrategyConstraints{}, &rejections)
+	if ok {
+		t.Fatal("combination with too few trades was accepted")
+	}
+	if result.OverallTradeCount != 2 {
+		t.Errorf("OverallTradeCount = %d, want 2", result.OverallTradeCount)
+	}
+}
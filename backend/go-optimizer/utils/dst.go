@@ -92,52 +92,3 @@ func getNthSunday(year int, month time.Month, nth int) time.Time {
 
 	return nthSunday
 }
-
-func GetServerTimeOffset(timeAsString string) int {
-	tradeTime, err := time.Parse("2006.01.02 15:04", timeAsString)
-	if err != nil {
-		return 0
-	}
-
-	//2. Get the DST info for the year of the trade for a specific location
-	// This location could also be part of the combination's settings.
-	dstInfoUS, err := GetDSTInfo(tradeTime.Year(), NewYork)
-	if err != nil {
-		return 0 // Unsupported location
-	}
-
-	//2. Get the DST info for the year of the trade for a specific location
-	// This location could also be part of the combination's settings.
-	dstInfoEU, err := GetDSTInfo(tradeTime.Year(), NewYork)
-	if err != nil {
-		return 0 // Unsupported location
-	}
-
-	var isEuDstActive bool
-	var isUsDstActive bool
-
-	if (tradeTime.Equal(dstInfoEU.StartUTC) || tradeTime.After(dstInfoEU.StartUTC)) && tradeTime.Before(dstInfoEU.EndUTC) {
-		isEuDstActive = true
-	}
-
-	if (tradeTime.Equal(dstInfoUS.StartUTC) || tradeTime.After(dstInfoUS.StartUTC)) && tradeTime.Before(dstInfoUS.EndUTC) {
-		isUsDstActive = true
-	}
-
-	usOffset := 2
-	euOffset := 2
-
-	if isUsDstActive && !isEuDstActive {
-		euOffset = euOffset + 1
-	}
-
-	if isEuDstActive && isUsDstActive {
-		euOffset = usOffset
-	}
-
-	if !isUsDstActive && !isEuDstActive {
-		euOffset = usOffset
-	}
-
-	return euOffset
-}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // Embed the IANA database, the container image does not ship one.
+)
+
+// MarketZones maps the exchange names used in settings to their IANA time zones.
+var MarketZones = map[string]string{
+	"Frankfurt": "Europe/Berlin",
+	"London":    "Europe/London",
+	"NewYork":   "America/New_York",
+}
+
+// BrokerClock converts broker server wall-clock times to UTC. Supported models:
+//   - "nyClose": server time is New York time + 7h (GMT+2/GMT+3), the MetaTrader default
+//   - "fixed": server time is UTC + OffsetHours all year
+//   - "zone": server time follows an IANA time zone
+//   - "euOffsColumn": server time is Frankfurt time + EU_OFFS hours, taken per trade
+//
+// EU_OFFS is the whole number of hours the broker server clock is ahead of Frankfurt local
+// time when the trade was opened. For a New York close server it is 1 most of the year and
+// 2 in the weeks New York is already, or still, on summer time while Europe is not.
+type BrokerClock struct {
+	Model       string
+	OffsetHours float64
+	zone        *time.Location
+}
+
+// NewBrokerClock builds the clock described by the "brokerTime" setting. A missing
+// setting selects the "nyClose" model.
+func NewBrokerClock(setting map[string]interface{}) (BrokerClock, error) {
+	clock := BrokerClock{Model: "nyClose"}
+	if model, ok := setting["model"].(string); ok && model != "" {
+		clock.Model = model
+	}
+
+	var zoneName string
+	switch clock.Model {
+	case "nyClose":
+		zoneName = MarketZones["NewYork"]
+	case "euOffsColumn":
+		zoneName = MarketZones["Frankfurt"]
+	case "zone":
+		zoneName, _ = setting["zone"].(string)
+		if zoneName == "" {
+			return BrokerClock{}, fmt.Errorf("broker time model 'zone' needs a zone")
+		}
+	case "fixed":
+		clock.OffsetHours, _ = setting["offsetHours"].(float64)
+		return clock, nil
+	default:
+		return BrokerClock{}, fmt.Errorf("unknown broker time model %q", clock.Model)
+	}
+
+	zone, err := time.LoadLocation(zoneName)
+	if err != nil {
+		return BrokerClock{}, fmt.Errorf("unknown time zone %q: %w", zoneName, err)
+	}
+	clock.zone = zone
+	return clock, nil
+}
+
+// ToUTC converts a broker wall-clock time (given with a UTC location) to the actual UTC
+// instant. euOffs is the trade's EU_OFFS value and only used by the "euOffsColumn" model.
+func (c BrokerClock) ToUTC(wall time.Time, euOffs int) time.Time {
+	switch c.Model {
+	case "fixed":
+		return wall.Add(-time.Duration(c.OffsetHours * float64(time.Hour)))
+	case "nyClose":
+		return inZone(wall.Add(-7*time.Hour), c.zone)
+	case "euOffsColumn":
+		return inZone(wall.Add(-time.Duration(euOffs)*time.Hour), c.zone)
+	default:
+		return inZone(wall, c.zone)
+	}
+}
+
+// inZone reinterprets the wall-clock fields of t in the given zone and returns the instant in UTC.
+func inZone(t time.Time, zone *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, zone).UTC()
+}
+
+// MinutesOfDay returns the minutes from midnight of t in the given zone.
+func MinutesOfDay(t time.Time, zone *time.Location) int {
+	local := t.In(zone)
+	return local.Hour()*60 + local.Minute()
+}
+
+// LoadMarketZone resolves a market name from MarketZones or an IANA zone name.
+func LoadMarketZone(name string) (*time.Location, error) {
+	if zoneName, ok := MarketZones[name]; ok {
+		name = zoneName
+	}
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	return zone, nil
+}
+
+// StandardMinutesOfDay returns the minutes from midnight of t in the standard (winter)
+// time of the given zone, ignoring daylight saving time. The standard offset is the
+// smaller of the offsets in January and July, which holds for both hemispheres.
+func StandardMinutesOfDay(t time.Time, zone *time.Location) int {
+	_, january := time.Date(t.Year(), time.January, 1, 12, 0, 0, 0, zone).Zone()
+	_, july := time.Date(t.Year(), time.July, 1, 12, 0, 0, 0, zone).Zone()
+	offset := january
+	if july < offset {
+		offset = july
+	}
+	local := t.UTC().Add(time.Duration(offset) * time.Second)
+	return local.Hour()*60 + local.Minute()
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBrokerClockToUTC(t *testing.T) {
+	wall := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	tests := []struct {
+		name    string
+		setting map[string]interface{}
+		wall    string
+		euOffs  int
+		want    string
+	}{
+		// New York and Europe switch to summer time on different Sundays; the broker clock
+		// follows New York, so it is GMT+3 in the weeks in between.
+		{"nyClose winter", nil, "2024-03-08 10:00", 0, "2024-03-08 08:00"},
+		{"nyClose US summer before EU summer", nil, "2024-03-12 10:00", 0, "2024-03-12 07:00"},
+		{"nyClose summer", nil, "2024-04-02 10:00", 0, "2024-04-02 07:00"},
+		{"nyClose EU winter before US winter", nil, "2024-10-29 10:00", 0, "2024-10-29 07:00"},
+		{"nyClose winter again", nil, "2024-11-05 10:00", 0, "2024-11-05 08:00"},
+
+		{"euOffsColumn winter", map[string]interface{}{"model": "euOffsColumn"}, "2024-03-08 10:00", 1, "2024-03-08 08:00"},
+		{"euOffsColumn US summer before EU summer", map[string]interface{}{"model": "euOffsColumn"}, "2024-03-12 10:00", 2, "2024-03-12 07:00"},
+		{"euOffsColumn summer", map[string]interface{}{"model": "euOffsColumn"}, "2024-04-02 10:00", 1, "2024-04-02 07:00"},
+
+		{"fixed", map[string]interface{}{"model": "fixed", "offsetHours": 2.0}, "2024-03-12 10:00", 0, "2024-03-12 08:00"},
+		{"zone winter", map[string]interface{}{"model": "zone", "zone": "Europe/London"}, "2024-03-29 12:00", 0, "2024-03-29 12:00"},
+		{"zone summer", map[string]interface{}{"model": "zone", "zone": "Europe/London"}, "2024-04-01 12:00", 0, "2024-04-01 11:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock, err := NewBrokerClock(tt.setting)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := clock.ToUTC(wall(tt.wall), tt.euOffs)
+			if want := wall(tt.want); !got.Equal(want) {
+				t.Errorf("ToUTC(%s) = %s, want %s", tt.wall, got.Format("2006-01-02 15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBrokerClockErrors(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"model": "zone"},
+		{"model": "zone", "zone": "Nowhere/Atlantis"},
+		{"model": "sundial"},
+	}
+	for _, setting := range tests {
+		if _, err := NewBrokerClock(setting); err == nil {
+			t.Errorf("NewBrokerClock(%v) returned no error", setting)
+		}
+	}
+}
+
+func TestMinutesOfDayAcrossDST(t *testing.T) {
+	newYork, err := LoadMarketZone("NewYork")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		instant  time.Time
+		local    int
+		standard int
+	}{
+		{"winter", time.Date(2024, time.March, 8, 14, 30, 0, 0, time.UTC), 9*60 + 30, 9*60 + 30},
+		{"first summer week", time.Date(2024, time.March, 12, 13, 30, 0, 0, time.UTC), 9*60 + 30, 8*60 + 30},
+		{"last summer week", time.Date(2024, time.October, 29, 13, 30, 0, 0, time.UTC), 9*60 + 30, 8*60 + 30},
+		{"winter again", time.Date(2024, time.November, 5, 14, 30, 0, 0, time.UTC), 9*60 + 30, 9*60 + 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MinutesOfDay(tt.instant, newYork); got != tt.local {
+				t.Errorf("MinutesOfDay = %d, want %d", got, tt.local)
+			}
+			if got := StandardMinutesOfDay(tt.instant, newYork); got != tt.standard {
+				t.Errorf("StandardMinutesOfDay = %d, want %d", got, tt.standard)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // TimeToMinutes converts an HH:mm string to minutes from midnight.
@@ -26,3 +27,31 @@ func MinutesToTime(minutes int) string {
 	m := minutes % 60
 	return fmt.Sprintf("%02d:%02d", h, m)
 }
+
+// tradeDateLayouts are the date formats found in the trade exports, MetaTrader's first.
+var tradeDateLayouts = []string{"2006.01.02", "2006-01-02", "02.01.2006", "2006/01/02"}
+
+// ParseTradeDate parses a trade's Date value into a date at midnight UTC.
+func ParseTradeDate(dateValue string) (time.Time, error) {
+	dateValue = strings.TrimSpace(dateValue)
+	// Timestamps exported as text carry a time part, which is not part of the date.
+	if idx := strings.IndexAny(dateValue, " T"); idx > 0 {
+		dateValue = dateValue[:idx]
+	}
+	for _, layout := range tradeDateLayouts {
+		if t, err := time.Parse(layout, dateValue); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date format: %s", dateValue)
+}
+
+// DateKey converts a date to its yyyymmdd integer form, which sorts chronologically.
+func DateKey(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
+
+// DateFromKey converts a yyyymmdd integer back to a date at midnight UTC.
+func DateFromKey(key int) time.Time {
+	return time.Date(key/10000, time.Month(key/100%100), key%100, 0, 0, 0, 0, time.UTC)
+}
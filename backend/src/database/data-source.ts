@@ -6,11 +6,13 @@ import { OptimizationResult } from "../entities/OptimizationResult";
 import { ArchivedResult } from "../entities/ArchivedResult";
 import { TemporaryResult } from "../entities/TemporaryResult";
 import { Tag } from "../entities/Tag";
+import { OptimizerCatalog } from "../entities/OptimizerCatalog";
+import { NewsEvent } from "../entities/NewsEvent";
 
 export const AppDataSource = new DataSource({
     type: "postgres",
     url: process.env.DATABASE_URL,
-    entities: [Trade, Configuration, OptimizationResult, ArchivedResult, TemporaryResult, Tag],
+    entities: [Trade, Configuration, OptimizationResult, ArchivedResult, TemporaryResult, Tag, OptimizerCatalog, NewsEvent],
     synchronize: true, // Auto-creates DB tables. Good for dev, but use migrations in production.
     logging: false,
     
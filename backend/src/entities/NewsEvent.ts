@@ -0,0 +1,22 @@
+import { Entity, PrimaryGeneratedColumn, Column, Index } from "typeorm";
+
+// Economic calendar events read by the Go optimizer for the news criteria,
+// unless a calendar file is configured with NEWS_CALENDAR_FILE.
+@Entity()
+export class NewsEvent {
+    @PrimaryGeneratedColumn()
+    id!: number;
+
+    @Index()
+    @Column({ type: 'timestamptz' })
+    timestamp!: Date;
+
+    @Column({ type: 'varchar', length: 8 })
+    currency!: string; // e.g., 'EUR', 'USD'
+
+    @Column({ type: 'varchar', length: 16 })
+    impact!: string; // 'high', 'medium' or 'low'
+
+    @Column({ type: 'varchar', length: 200, nullable: true })
+    title!: string | null;
+}
@@ -7,8 +7,8 @@ export class OptimizationResult {
     id!: number;
 
     @Index()
-    @Column({ type: 'varchar', length: 20 })
-    instrument!: string;
+    @Column({ type: 'varchar', length: 100 })
+    instrument!: string; // Comma-separated for multi-instrument runs; the optimizer checks the length before a run
 
     @ManyToOne(() => Configuration, { eager: true })
     configuration!: Configuration;
@@ -16,6 +16,14 @@ export class OptimizationResult {
     @Column({ type: 'jsonb' })
     results!: object;
 
+    // The rest of the optimizer output: warnings, run summary, portfolios and overlap.
+    @Column({ type: 'jsonb', nullable: true })
+    summary!: object | null;
+
+    // Trade ids and equity curves per result index, with the 'tradeDetails': 'file' setting.
+    @Column({ type: 'jsonb', nullable: true })
+    tradeDetails!: object | null;
+
     // --- NEW COLUMN ---
     @Column({ type: 'timestamp' })
     startedAt!: Date;
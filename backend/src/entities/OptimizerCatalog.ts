@@ -0,0 +1,19 @@
+import { Entity, PrimaryColumn, Column, UpdateDateColumn } from "typeorm";
+
+// Catalog documents read by the Go optimizer at startup, e.g. 'selectableCombinations'
+// and 'tradeStrategies'. Missing documents are seeded by the optimizer from its built-in lists.
+@Entity()
+export class OptimizerCatalog {
+    @PrimaryColumn({ type: 'varchar', length: 64 })
+    name!: string;
+
+    @Column({ type: 'jsonb' })
+    document!: object;
+
+    // Built-in seed version the document was stored or last upgraded with by the optimizer.
+    @Column({ type: 'int', default: 0 })
+    version!: number;
+
+    @UpdateDateColumn()
+    updatedAt!: Date;
+}
@@ -129,6 +129,8 @@ export class Trade {
     @Column()
     M5_Candle!: boolean;
     
+    // The *_Consecutive_Candles counts are signed: positive for a run of bullish candles,
+    // negative for a bearish run. The optimizer reads them relative to the trade direction.
     @Column({ type: 'integer' })
     M5_Consecutive_Candles!: number;
     
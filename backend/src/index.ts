@@ -8,6 +8,7 @@ import optimizationRoutes from './routes/optimizationRoutes';
 import resultRoutes from './routes/resultRoutes';
 import archiveRoutes from './routes/archiveRoutes';
 import tagRoutes from './routes/tagRoutes';
+import catalogRoutes from './routes/catalogRoutes';
 
 const app = express();
 const port = 3000;
@@ -23,6 +24,7 @@ app.use('/api/optimize', optimizationRoutes);
 app.use('/api/results', resultRoutes);
 app.use('/api/archive', archiveRoutes); // Add this
 app.use('/api/tags', tagRoutes);
+app.use('/api/catalog', catalogRoutes);
 
 // A simple test route
 app.get('/', (req, res) => {
@@ -3,6 +3,7 @@ import { AppDataSource } from '../database/data-source';
 import { Configuration } from '../entities/Configuration';
 import { OptimizationResult } from '../entities/OptimizationResult';
 import { spawn } from 'child_process';
+import { promises as fs } from 'fs';
 import path from 'path';
 import IORedis from 'ioredis';
 import { redisConnection } from './redisConnection';
@@ -93,14 +94,39 @@ export default async function(job: Job): Promise<any> {
             throw new Error(`Go optimizer exited with code ${code}. Stderr: ${stderr}`);
         }
         
-        const finalResults = JSON.parse(stdout); // Only parse stdout on success
+        // With OPTIMIZER_RESULT_SINK=database the optimizer stores the result itself,
+        // in a transaction, and prints only the id of the new row.
+        if (process.env.OPTIMIZER_RESULT_SINK === 'database') {
+            const resultId = parseInt(stdout.trim(), 10);
+            if (isNaN(resultId)) {
+                throw new Error(`Go optimizer printed no result id. Stdout: ${stdout.slice(0, 200)}`);
+            }
+            console.log(`Go optimizer finished successfully. Stored optimization result ${resultId}.`);
+            console.log(`--- JOB ${job.id} FINISHED SUCCESSFULLY ---`);
+            return { success: true, resultId };
+        }
+
+        const output = JSON.parse(stdout); // Only parse stdout on success
+        // The optimizer prints an envelope with the results and the run summary; builds
+        // with bare output print only the results array.
+        const { results: finalResults, tradeDetailsFile, ...summary } = Array.isArray(output) ? { results: output } : output;
         console.log(`Go optimizer finished successfully. Found ${finalResults.length} top results.`);
 
+        // With 'tradeDetails': 'file' the trade details come in a side file, stored with the
+        // result and removed.
+        let tradeDetails = null;
+        if (tradeDetailsFile) {
+            tradeDetails = JSON.parse(await fs.readFile(tradeDetailsFile, 'utf8'));
+            await fs.unlink(tradeDetailsFile);
+        }
+
         // Step 3: Save the final result
         const newResult = resultRepo.create({
             instrument: instrument,
             configuration: config,
             results: finalResults,
+            summary: Object.keys(summary).length > 0 ? summary : null,
+            tradeDetails,
             startedAt: startTime,
         });
         await resultRepo.save(newResult);
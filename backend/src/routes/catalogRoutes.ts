@@ -0,0 +1,87 @@
+import { Router } from "express";
+import { AppDataSource } from "../database/data-source";
+import { OptimizerCatalog } from "../entities/OptimizerCatalog";
+
+const router = Router();
+
+// The catalog documents the optimizer reads; it seeds each of them on its first run.
+const CATALOG_NAMES = ["selectableCombinations", "tradeStrategies", "tradingSessions"];
+
+/**
+ * Checks that a catalog document is a list of entries with unique names, and returns
+ * the problem if not. The optimizer validates the rest of each entry on its next run.
+ */
+function catalogDocumentError(document: unknown): string | null {
+    if (!Array.isArray(document)) {
+        return "A catalog document must be an array.";
+    }
+    const names = new Set<string>();
+    for (const [i, entry] of document.entries()) {
+        if (typeof entry !== "object" || entry === null || Array.isArray(entry)) {
+            return `Catalog entry ${i} must be an object.`;
+        }
+        const name = (entry as Record<string, unknown>).name;
+        if (typeof name !== "string" || name === "") {
+            return `Catalog entry ${i} has no name.`;
+        }
+        if (names.has(name)) {
+            return `Catalog entry "${name}" is defined twice.`;
+        }
+        names.add(name);
+    }
+    return null;
+}
+
+/**
+ * @route   GET /api/catalog
+ * @desc    Get all optimizer catalog documents, keyed by name
+ */
+router.get("/", async (req, res) => {
+    const catalogRepo = AppDataSource.getRepository(OptimizerCatalog);
+    try {
+        const catalogs = await catalogRepo.find();
+        const documents: Record<string, object> = {};
+        for (const catalog of catalogs) {
+            documents[catalog.name] = catalog.document;
+        }
+        res.json(documents);
+    } catch (error) {
+        console.error("Error fetching catalogs:", error);
+        res.status(500).json({ message: "Error fetching catalogs" });
+    }
+});
+
+/**
+ * @route   PUT /api/catalog/:name
+ * @desc    Replace a catalog document. The optimizer validates it on its next run.
+ *          The document keeps its seed version, so the optimizer does not take the
+ *          user's save for an outdated seed.
+ */
+router.put("/:name", async (req, res) => {
+    const { name } = req.params;
+    const { document } = req.body;
+
+    if (!CATALOG_NAMES.includes(name)) {
+        return res.status(404).json({ message: `Unknown catalog "${name}".` });
+    }
+    const documentError = catalogDocumentError(document);
+    if (documentError) {
+        return res.status(400).json({ message: documentError });
+    }
+
+    const catalogRepo = AppDataSource.getRepository(OptimizerCatalog);
+    try {
+        const catalog = await catalogRepo.findOneBy({ name });
+        if (!catalog) {
+            return res.status(409).json({ message: `Catalog "${name}" has not been seeded yet; run the optimizer once first.` });
+        }
+        catalog.document = document;
+        await catalogRepo.save(catalog);
+        res.json(catalog);
+    } catch (error) {
+        console.error("Error saving catalog:", error);
+        res.status(500).json({ message: "Error saving catalog" });
+    }
+});
+
+export default router;
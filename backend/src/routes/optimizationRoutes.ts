@@ -123,7 +123,8 @@ router.post("/:configId", async (req, res) => {
         // --- Create the enriched data payload ---
         const jobData = {
             configId: config.id,
-            instrument: config.instrument,
+            // An "instruments" list in the settings runs all of them in one optimizer process.
+            instrument: (config.settings as any)?.instruments?.join(',') || config.instrument,
             configurationName: config.name,
             maxCombinationsToTest: (config.settings as any)?.maxCombinationsToTest || 100000,
             highPriority: highPriority
@@ -2,5 +2,7 @@
 export function getPredefinedFilter(config: any, filterName: 'Setup' | 'Session'): string | null {
     const filters = config.settings?.predefinedFilters || [];
     const found = filters.find((f: any) => f.columnHeader === filterName);
-    return found ? found.condition : null;
+    if (!found) return null;
+    // Multi-setup runs filter on a list of setups.
+    return Array.isArray(found.condition) ? found.condition.join(', ') : found.condition;
 }
\ No newline at end of file